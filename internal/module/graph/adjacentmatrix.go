@@ -19,11 +19,17 @@ package graph
 // AdjacentMatrix represents a directed graph through an adjacent matrix.
 type AdjacentMatrix interface {
 	// AddEdge adds a named edge between vertex p and vertex c.
+	// Adding an already existing edge has no effect.
 	AddEdge(name string, p Vertex, c Vertex)
 	// AddEdges adds a named edge between vertex p and vertices c.
+	// Already existing edges are not added again.
 	AddEdges(name string, p Vertex, c []Vertex)
 	// Get gets all vertices of a named edge on vertex v.
 	Get(name string, v Vertex) []Vertex
+	// Parents gets all vertices having a named edge, in no particular order.
+	Parents(name string) []Vertex
+	// RemoveVertex removes vertex v from all named edges, both as parent and as child.
+	RemoveVertex(v Vertex)
 	// NumberOfEdges gets the number of named edges.
 	NumberOfEdges(name string) int
 }
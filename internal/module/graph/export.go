@@ -0,0 +1,164 @@
+/*
+Copyright © 2021 The OpenDependency Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package graph
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// edge represents a single directed named edge between two vertices.
+type edge struct {
+	name string
+	from Vertex
+	to   Vertex
+}
+
+// exportedEdges are the named edges rendered by exporters.
+// The used-by and require edges are omitted as they only reverse depends-on and required-for edges.
+var exportedEdges = []string{dependsOnEdge, requiredForEdge}
+
+// dotEdgeStyles maps exported edge names to their DOT style.
+var dotEdgeStyles = map[string]string{
+	dependsOnEdge:   "solid",
+	requiredForEdge: "dashed",
+}
+
+// mermaidEdgeArrows maps exported edge names to their Mermaid arrow.
+var mermaidEdgeArrows = map[string]string{
+	dependsOnEdge:   "-->",
+	requiredForEdge: "-.->",
+}
+
+// snapshot returns all vertices and exported edges of the graph sorted by their string representation.
+func (g *graph) snapshot() ([]Vertex, []edge) {
+	seen := map[Vertex]bool{}
+
+	g.metadataMux.RLock()
+	for v := range g.metadata {
+		seen[v] = true
+	}
+	g.metadataMux.RUnlock()
+
+	var edges []edge
+	for _, name := range exportedEdges {
+		for _, p := range g.m.Parents(name) {
+			seen[p] = true
+			for _, c := range g.m.Get(name, p) {
+				seen[c] = true
+				edges = append(edges, edge{name: name, from: p, to: c})
+			}
+		}
+	}
+
+	vertices := make([]Vertex, 0, len(seen))
+	for v := range seen {
+		vertices = append(vertices, v)
+	}
+
+	sort.Slice(vertices, func(i, j int) bool {
+		return vertices[i].String() < vertices[j].String()
+	})
+	sort.Slice(edges, func(i, j int) bool {
+		if a, b := edges[i].from.String(), edges[j].from.String(); a != b {
+			return a < b
+		}
+		if a, b := edges[i].to.String(), edges[j].to.String(); a != b {
+			return a < b
+		}
+		return edges[i].name < edges[j].name
+	})
+
+	return vertices, edges
+}
+
+func (g *graph) ToDOT(w io.Writer) error {
+	vertices, edges := g.snapshot()
+
+	b := bufio.NewWriter(w)
+	fmt.Fprintln(b, "digraph odep {")
+	for _, v := range vertices {
+		fmt.Fprintf(b, "  %s [label=%s];\n", dotQuote(v.String()), dotQuote(v.String()))
+	}
+	for _, e := range edges {
+		fmt.Fprintf(b, "  %s -> %s [label=%s, style=%s];\n", dotQuote(e.from.String()), dotQuote(e.to.String()), dotQuote(e.name), dotEdgeStyles[e.name])
+	}
+	fmt.Fprintln(b, "}")
+
+	if err := b.Flush(); err != nil {
+		return fmt.Errorf("could not write dot: %w", err)
+	}
+	return nil
+}
+
+// dotQuote quotes s as DOT identifier.
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+func (g *graph) ToMermaid(w io.Writer) error {
+	vertices, edges := g.snapshot()
+
+	// ids are derived from the vertex alone, so that adding a vertex never changes the id of another one
+	ids := make(map[Vertex]string, len(vertices))
+	for _, v := range vertices {
+		ids[v] = mermaidID(v.String())
+	}
+
+	b := bufio.NewWriter(w)
+	fmt.Fprintln(b, "graph LR")
+	for _, v := range vertices {
+		fmt.Fprintf(b, "  %s[\"%s\"]\n", ids[v], strings.ReplaceAll(v.String(), `"`, "#quot;"))
+	}
+	for _, e := range edges {
+		fmt.Fprintf(b, "  %s %s|%s| %s\n", ids[e.from], mermaidEdgeArrows[e.name], e.name, ids[e.to])
+	}
+
+	if err := b.Flush(); err != nil {
+		return fmt.Errorf("could not write mermaid: %w", err)
+	}
+	return nil
+}
+
+// mermaidIDEscapes maps common coordinate characters to their escape sequence within Mermaid node ids.
+var mermaidIDEscapes = map[rune]string{
+	'_': "__",
+	'.': "_d",
+	':': "_c",
+	'/': "_s",
+	'-': "_h",
+}
+
+// mermaidID escapes all characters not allowed within Mermaid node ids.
+// The escaping is injective, so that distinct strings always result in distinct ids.
+func mermaidID(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9'):
+			b.WriteRune(r)
+		case mermaidIDEscapes[r] != "":
+			b.WriteString(mermaidIDEscapes[r])
+		default:
+			fmt.Fprintf(&b, "_u%06x", r)
+		}
+	}
+	return b.String()
+}
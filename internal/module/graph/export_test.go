@@ -0,0 +1,159 @@
+/*
+Copyright © 2021 The OpenDependency Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package graph
+
+import (
+	"bytes"
+	"fmt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	spec "github.com/opendependency/go-spec/pkg/spec/v1"
+)
+
+var _ = Describe("export", func() {
+
+	var (
+		g *graph
+	)
+
+	BeforeEach(func() {
+		g = NewGraph(NewInMemoryAdjacentMatrix())
+
+		downstreamDirection := spec.DependencyDirection_DOWNSTREAM
+
+		for _, mod := range []*spec.Module{
+			{
+				Namespace: "com.example",
+				Name:      "product",
+				Type:      "helm",
+				Version:   &spec.ModuleVersion{Name: "v1.5.0"},
+				Dependencies: []*spec.ModuleDependency{
+					{Namespace: "com.example", Name: "product", Type: "go", Version: "v1.5.0"},
+				},
+			},
+			{
+				Namespace: "com.example",
+				Name:      "product",
+				Type:      "go",
+				Version:   &spec.ModuleVersion{Name: "v1.5.0"},
+				Dependencies: []*spec.ModuleDependency{
+					{Namespace: "com.example", Name: "product", Type: "protobuf", Version: "v1.0.0", Direction: &downstreamDirection},
+				},
+			},
+		} {
+			if err := g.AddModule(mod); err != nil {
+				Fail(err.Error())
+			}
+		}
+	})
+
+	Context("to dot", func() {
+
+		When("graph is empty", func() {
+			It("writes an empty digraph", func() {
+				var buf bytes.Buffer
+
+				Expect(NewGraph(NewInMemoryAdjacentMatrix()).ToDOT(&buf)).To(BeNil())
+				Expect(buf.String()).To(Equal("digraph odep {\n}\n"))
+			})
+		})
+
+		When("graph is not empty", func() {
+			It("writes all vertices and edges styled per edge kind", func() {
+				var buf bytes.Buffer
+
+				Expect(g.ToDOT(&buf)).To(BeNil())
+				Expect(buf.String()).To(Equal(`digraph odep {
+  "com.example:product:go:v1.5.0" [label="com.example:product:go:v1.5.0"];
+  "com.example:product:helm:v1.5.0" [label="com.example:product:helm:v1.5.0"];
+  "com.example:product:protobuf:v1.0.0" [label="com.example:product:protobuf:v1.0.0"];
+  "com.example:product:go:v1.5.0" -> "com.example:product:protobuf:v1.0.0" [label="required-for", style=dashed];
+  "com.example:product:helm:v1.5.0" -> "com.example:product:go:v1.5.0" [label="depends-on", style=solid];
+}
+`))
+			})
+		})
+	})
+
+	Context("to mermaid", func() {
+
+		When("graph is empty", func() {
+			It("writes an empty flowchart", func() {
+				var buf bytes.Buffer
+
+				Expect(NewGraph(NewInMemoryAdjacentMatrix()).ToMermaid(&buf)).To(BeNil())
+				Expect(buf.String()).To(Equal("graph LR\n"))
+			})
+		})
+
+		When("graph is not empty", func() {
+			It("writes all vertices with sanitized ids and edges in semantic direction", func() {
+				var buf bytes.Buffer
+
+				Expect(g.ToMermaid(&buf)).To(BeNil())
+				Expect(buf.String()).To(Equal(`graph LR
+  com_dexample_cproduct_cgo_cv1_d5_d0["com.example:product:go:v1.5.0"]
+  com_dexample_cproduct_chelm_cv1_d5_d0["com.example:product:helm:v1.5.0"]
+  com_dexample_cproduct_cprotobuf_cv1_d0_d0["com.example:product:protobuf:v1.0.0"]
+  com_dexample_cproduct_cgo_cv1_d5_d0 -.->|required-for| com_dexample_cproduct_cprotobuf_cv1_d0_d0
+  com_dexample_cproduct_chelm_cv1_d5_d0 -->|depends-on| com_dexample_cproduct_cgo_cv1_d5_d0
+`))
+			})
+		})
+
+		When("vertices only differ in escaped characters", func() {
+			It("writes distinct ids which do not change when vertices are added", func() {
+				var before, after bytes.Buffer
+
+				Expect(g.ToMermaid(&before)).To(BeNil())
+				Expect(g.AddModule(&spec.Module{
+					Namespace: "com.example",
+					Name:      "product",
+					Type:      "go",
+					Version:   &spec.ModuleVersion{Name: "v1-5-0"},
+				})).To(BeNil())
+				Expect(g.ToMermaid(&after)).To(BeNil())
+
+				Expect(before.String()).To(ContainSubstring(`com_dexample_cproduct_cgo_cv1_d5_d0["com.example:product:go:v1.5.0"]`))
+				Expect(after.String()).To(ContainSubstring(`com_dexample_cproduct_cgo_cv1_d5_d0["com.example:product:go:v1.5.0"]`))
+				Expect(after.String()).To(ContainSubstring(`com_dexample_cproduct_cgo_cv1_h5_h0["com.example:product:go:v1-5-0"]`))
+			})
+		})
+
+		Context("mermaid id", func() {
+
+			for _, tt := range []struct {
+				s        string
+				expected string
+			}{
+				{s: "abcXYZ019", expected: "abcXYZ019"},
+				{s: "a_b", expected: "a__b"},
+				{s: "a.b:c/d-e", expected: "a_db_cc_sd_he"},
+				{s: "a+b", expected: "a_u00002bb"},
+				{s: "a_d", expected: "a__d"},
+			} {
+				tt := tt
+				When(fmt.Sprintf("string is %q", tt.s), func() {
+					It("escapes it injectively", func() {
+						Expect(mermaidID(tt.s)).To(Equal(tt.expected))
+					})
+				})
+			}
+		})
+	})
+})
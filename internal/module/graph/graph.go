@@ -17,9 +17,11 @@ limitations under the License.
 package graph
 
 import (
-	"container/list"
 	"errors"
 	"fmt"
+	"io"
+	"strings"
+	"sync"
 
 	spec "github.com/opendependency/go-spec/pkg/spec/v1"
 )
@@ -36,10 +38,62 @@ func (v *Vertex) String() string {
 	return fmt.Sprintf("%s:%s:%s:%s", v.Namespace, v.Name, v.Type, v.Version)
 }
 
+// NormalizeRef trims surrounding whitespace of every segment of the given module reference.
+// A single coordinate like a module name is treated as reference with one segment.
+func NormalizeRef(ref string) string {
+	segments := strings.Split(ref, ":")
+	for i, segment := range segments {
+		segments[i] = strings.TrimSpace(segment)
+	}
+	return strings.Join(segments, ":")
+}
+
+// ParseVertex parses a vertex from its string representation <namespace>:<name>:<type>:<version>.
+func ParseVertex(s string) (Vertex, error) {
+	segments := strings.Split(s, ":")
+	if len(segments) != 4 {
+		return Vertex{}, fmt.Errorf("invalid module reference %q; expected <namespace>:<name>:<type>:<version>", s)
+	}
+
+	for _, segment := range segments {
+		if segment == "" {
+			return Vertex{}, fmt.Errorf("invalid module reference %q; expected <namespace>:<name>:<type>:<version>", s)
+		}
+		if segment != strings.ToLower(segment) {
+			return Vertex{}, fmt.Errorf("invalid module reference %q; segment %q must be lowercase", s, segment)
+		}
+	}
+
+	return Vertex{
+		Namespace: segments[0],
+		Name:      segments[1],
+		Type:      segments[2],
+		Version:   segments[3],
+	}, nil
+}
+
+// VertexMetadata represents module data of a vertex which is not part of the vertex itself.
+type VertexMetadata struct {
+	// Annotations are the module annotations.
+	Annotations map[string]string
+	// Schema is the module version schema or empty if not set.
+	Schema string
+	// DependencyCount is the number of declared module dependencies.
+	DependencyCount int
+}
+
 // Graph represents a module graph containing all edges to other modules.
 type Graph interface {
+	ReadOnlyGraph
 	// AddModule adds the given module.
 	AddModule(module *spec.Module) error
+}
+
+// ReadOnlyGraph represents the read operations of a module graph.
+type ReadOnlyGraph interface {
+	// Metadata gets the metadata of the module added as vertex v.
+	// Vertices only known as dependency of other modules have no metadata.
+	Metadata(v Vertex) (VertexMetadata, bool)
 	// TraverseDependOnEdgesBFS begins at vertex s and traverse over all depend-on edges
 	// using breadth-first search.
 	// The given function fn is called for each vertex and its direct depend-on edge vertices.
@@ -88,6 +142,59 @@ type Graph interface {
 	// The function fn returning true continues the traversal while returning false stops the traversal.
 	// The first function fn call has an empty vertex as parent p.
 	TraverseRequireEdgesDFS(s Vertex, fn func(p Vertex, v Vertex) bool)
+	// TraverseDependOnEdgesBFSWithDepth behaves like TraverseDependOnEdgesBFS but does not traverse
+	// depend-on edges of vertices at maxDepth, which are passed to fn without children. A negative maxDepth means unlimited.
+	// The function fn additionally receives the depth of parent p, with vertex s at depth 0.
+	TraverseDependOnEdgesBFSWithDepth(s Vertex, maxDepth int, fn func(p Vertex, v []Vertex, depth int) bool)
+	// TraverseUsedByEdgesBFSWithDepth behaves like TraverseUsedByEdgesBFS but does not traverse
+	// used-by edges of vertices at maxDepth, which are passed to fn without children. A negative maxDepth means unlimited.
+	// The function fn additionally receives the depth of parent p, with vertex s at depth 0.
+	TraverseUsedByEdgesBFSWithDepth(s Vertex, maxDepth int, fn func(p Vertex, v []Vertex, depth int) bool)
+	// TraverseRequiredForEdgesBFSWithDepth behaves like TraverseRequiredForEdgesBFS but does not traverse
+	// required-for edges of vertices at maxDepth, which are passed to fn without children. A negative maxDepth means unlimited.
+	// The function fn additionally receives the depth of parent p, with vertex s at depth 0.
+	TraverseRequiredForEdgesBFSWithDepth(s Vertex, maxDepth int, fn func(p Vertex, v []Vertex, depth int) bool)
+	// TraverseRequireEdgesBFSWithDepth behaves like TraverseRequireEdgesBFS but does not traverse
+	// require edges of vertices at maxDepth, which are passed to fn without children. A negative maxDepth means unlimited.
+	// The function fn additionally receives the depth of parent p, with vertex s at depth 0.
+	TraverseRequireEdgesBFSWithDepth(s Vertex, maxDepth int, fn func(p Vertex, v []Vertex, depth int) bool)
+	// CollectDependOn returns all vertices reachable from vertex s over depend-on edges
+	// in breadth-first order, excluding vertex s.
+	CollectDependOn(s Vertex) []Vertex
+	// CollectDependOnTree returns the depend-on edge vertices of every vertex reachable from vertex s
+	// which has at least one depend-on edge.
+	CollectDependOnTree(s Vertex) map[Vertex][]Vertex
+	// CollectUsedBy returns all vertices reachable from vertex s over used-by edges
+	// in breadth-first order, excluding vertex s.
+	CollectUsedBy(s Vertex) []Vertex
+	// CollectUsedByTree returns the used-by edge vertices of every vertex reachable from vertex s
+	// which has at least one used-by edge.
+	CollectUsedByTree(s Vertex) map[Vertex][]Vertex
+	// CollectRequiredFor returns all vertices reachable from vertex s over required-for edges
+	// in breadth-first order, excluding vertex s.
+	CollectRequiredFor(s Vertex) []Vertex
+	// CollectRequiredForTree returns the required-for edge vertices of every vertex reachable from vertex s
+	// which has at least one required-for edge.
+	CollectRequiredForTree(s Vertex) map[Vertex][]Vertex
+	// CollectRequire returns all vertices reachable from vertex s over require edges
+	// in breadth-first order, excluding vertex s.
+	CollectRequire(s Vertex) []Vertex
+	// CollectRequireTree returns the require edge vertices of every vertex reachable from vertex s
+	// which has at least one require edge.
+	CollectRequireTree(s Vertex) map[Vertex][]Vertex
+	// FindCycles returns every cycle over depend-on edges detected while searching from vertex s.
+	// Each cycle is ordered along its depend-on edges, beginning with the vertex reached first.
+	// A vertex depending on itself is returned as cycle with a single vertex.
+	FindCycles(s Vertex) [][]Vertex
+	// ShortestPath returns the shortest path from vertex from to vertex to over edges of the given kind,
+	// including both vertices. It returns false if vertex to is not reachable from vertex from.
+	ShortestPath(kind EdgeKind, from Vertex, to Vertex) ([]Vertex, bool)
+	// ToDOT writes the whole graph in Graphviz DOT format to w.
+	// Depend-on edges are rendered solid and required-for edges dashed.
+	ToDOT(w io.Writer) error
+	// ToMermaid writes the whole graph as Mermaid flowchart to w.
+	// Depend-on edges are rendered solid and required-for edges dotted.
+	ToMermaid(w io.Writer) error
 }
 
 const (
@@ -105,10 +212,25 @@ const (
 	requireEdge = "require"
 )
 
+// EdgeKind represents the kind of edges between vertices.
+type EdgeKind string
+
+const (
+	// DependsOnEdgeKind selects depends-on edges.
+	DependsOnEdgeKind EdgeKind = dependsOnEdge
+	// UsedByEdgeKind selects used-by edges.
+	UsedByEdgeKind EdgeKind = usedByEdge
+	// RequiredForEdgeKind selects required-for edges.
+	RequiredForEdgeKind EdgeKind = requiredForEdge
+	// RequireEdgeKind selects require edges.
+	RequireEdgeKind EdgeKind = requireEdge
+)
+
 // NewGraph creates a new graph with the given AdjacentMatrix as underlying matrix.
 func NewGraph(m AdjacentMatrix) *graph {
 	return &graph{
-		m: m,
+		m:        m,
+		metadata: map[Vertex]VertexMetadata{},
 	}
 }
 
@@ -116,6 +238,9 @@ var _ Graph = (*graph)(nil)
 
 type graph struct {
 	m AdjacentMatrix
+	// metadata is kept apart from the matrix as vertices are used as keys.
+	metadataMux sync.RWMutex
+	metadata    map[Vertex]VertexMetadata
 }
 
 func (g *graph) AddModule(module *spec.Module) error {
@@ -134,6 +259,19 @@ func (g *graph) AddModule(module *spec.Module) error {
 		Version:   module.Version.Name,
 	}
 
+	annotations := make(map[string]string, len(module.Annotations))
+	for k, v := range module.Annotations {
+		annotations[k] = v
+	}
+
+	g.metadataMux.Lock()
+	g.metadata[p] = VertexMetadata{
+		Annotations:     annotations,
+		Schema:          module.Version.GetSchema(),
+		DependencyCount: len(module.Dependencies),
+	}
+	g.metadataMux.Unlock()
+
 	for _, dependency := range module.Dependencies {
 		v := Vertex{
 			Namespace: dependency.Namespace,
@@ -154,6 +292,13 @@ func (g *graph) AddModule(module *spec.Module) error {
 	return nil
 }
 
+func (g *graph) Metadata(v Vertex) (VertexMetadata, bool) {
+	g.metadataMux.RLock()
+	defer g.metadataMux.RUnlock()
+	metadata, ok := g.metadata[v]
+	return metadata, ok
+}
+
 func (g *graph) TraverseDependOnEdgesBFS(s Vertex, fn func(p Vertex, v []Vertex) bool) {
 	g.traverseBFS(dependsOnEdge, s, fn)
 }
@@ -186,33 +331,237 @@ func (g *graph) TraverseRequireEdgesDFS(s Vertex, fn func(p Vertex, v Vertex) bo
 	g.traverseDFS(requireEdge, s, fn)
 }
 
+func (g *graph) TraverseDependOnEdgesBFSWithDepth(s Vertex, maxDepth int, fn func(p Vertex, v []Vertex, depth int) bool) {
+	g.traverseBFSWithDepth(dependsOnEdge, s, maxDepth, fn)
+}
+
+func (g *graph) TraverseUsedByEdgesBFSWithDepth(s Vertex, maxDepth int, fn func(p Vertex, v []Vertex, depth int) bool) {
+	g.traverseBFSWithDepth(usedByEdge, s, maxDepth, fn)
+}
+
+func (g *graph) TraverseRequiredForEdgesBFSWithDepth(s Vertex, maxDepth int, fn func(p Vertex, v []Vertex, depth int) bool) {
+	g.traverseBFSWithDepth(requiredForEdge, s, maxDepth, fn)
+}
+
+func (g *graph) TraverseRequireEdgesBFSWithDepth(s Vertex, maxDepth int, fn func(p Vertex, v []Vertex, depth int) bool) {
+	g.traverseBFSWithDepth(requireEdge, s, maxDepth, fn)
+}
+
+func (g *graph) CollectDependOn(s Vertex) []Vertex {
+	return g.collect(dependsOnEdge, s)
+}
+
+func (g *graph) CollectDependOnTree(s Vertex) map[Vertex][]Vertex {
+	return g.collectTree(dependsOnEdge, s)
+}
+
+func (g *graph) CollectUsedBy(s Vertex) []Vertex {
+	return g.collect(usedByEdge, s)
+}
+
+func (g *graph) CollectUsedByTree(s Vertex) map[Vertex][]Vertex {
+	return g.collectTree(usedByEdge, s)
+}
+
+func (g *graph) CollectRequiredFor(s Vertex) []Vertex {
+	return g.collect(requiredForEdge, s)
+}
+
+func (g *graph) CollectRequiredForTree(s Vertex) map[Vertex][]Vertex {
+	return g.collectTree(requiredForEdge, s)
+}
+
+func (g *graph) CollectRequire(s Vertex) []Vertex {
+	return g.collect(requireEdge, s)
+}
+
+func (g *graph) CollectRequireTree(s Vertex) map[Vertex][]Vertex {
+	return g.collectTree(requireEdge, s)
+}
+
+func (g *graph) ShortestPath(kind EdgeKind, from Vertex, to Vertex) ([]Vertex, bool) {
+	// track the parent through which each vertex has been reached first
+	parents := map[Vertex]Vertex{}
+	found := from == to
+
+	g.traverseBFS(string(kind), from, func(p Vertex, v []Vertex) bool {
+		for _, child := range v {
+			if _, ok := parents[child]; ok || child == from {
+				continue
+			}
+			parents[child] = p
+			if child == to {
+				found = true
+				return false
+			}
+		}
+		return !found
+	})
+
+	if !found {
+		return nil, false
+	}
+
+	path := []Vertex{to}
+	for v := to; v != from; {
+		v = parents[v]
+		path = append(path, v)
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+
+	return path, true
+}
+
+// vertexColor marks the state of a vertex during cycle detection.
+type vertexColor int
+
+const (
+	// white vertices have not been visited yet.
+	white vertexColor = iota
+	// gray vertices are on the current search path.
+	gray
+	// black vertices and all their descendants have been visited.
+	black
+)
+
+func (g *graph) FindCycles(s Vertex) [][]Vertex {
+	var cycles [][]Vertex
+
+	colors := map[Vertex]vertexColor{}
+	var path []Vertex
+
+	var visit func(v Vertex)
+	visit = func(v Vertex) {
+		colors[v] = gray
+		path = append(path, v)
+
+		for _, child := range g.m.Get(dependsOnEdge, v) {
+			switch colors[child] {
+			case white:
+				visit(child)
+			case gray:
+				// back-edge: the path from child to v forms a cycle
+				for i := len(path) - 1; i >= 0; i-- {
+					if path[i] == child {
+						cycle := make([]Vertex, len(path)-i)
+						copy(cycle, path[i:])
+						cycles = append(cycles, cycle)
+						break
+					}
+				}
+			}
+		}
+
+		path = path[:len(path)-1]
+		colors[v] = black
+	}
+
+	visit(s)
+
+	return cycles
+}
+
+func (g *graph) collect(edgeName string, s Vertex) []Vertex {
+	var vertices []Vertex
+
+	g.traverseBFS(edgeName, s, func(p Vertex, v []Vertex) bool {
+		if p != s {
+			vertices = append(vertices, p)
+		}
+		return true
+	})
+
+	return vertices
+}
+
+func (g *graph) collectTree(edgeName string, s Vertex) map[Vertex][]Vertex {
+	tree := map[Vertex][]Vertex{}
+
+	g.traverseBFS(edgeName, s, func(p Vertex, v []Vertex) bool {
+		if len(v) > 0 {
+			// copy the children so that callers cannot modify the adjacent matrix
+			tree[p] = append([]Vertex(nil), v...)
+		}
+		return true
+	})
+
+	return tree
+}
+
 func (g *graph) traverseBFS(edgeName string, s Vertex, fn func(p Vertex, v []Vertex) bool) {
+	g.traverseBFSWithDepth(edgeName, s, -1, func(p Vertex, v []Vertex, depth int) bool {
+		return fn(p, v)
+	})
+}
+
+// queuedVertex is a vertex waiting for its breadth-first visit at the given depth.
+type queuedVertex struct {
+	v     Vertex
+	depth int
+}
+
+// vertexQueue is a FIFO ring buffer of queued vertices, which reuses the slots of consumed vertices.
+type vertexQueue struct {
+	items []queuedVertex
+	head  int
+	n     int
+}
+
+func (q *vertexQueue) len() int {
+	return q.n
+}
+
+func (q *vertexQueue) push(v queuedVertex) {
+	if q.n == len(q.items) {
+		items := make([]queuedVertex, 2*len(q.items)+1)
+		n := copy(items, q.items[q.head:])
+		copy(items[n:], q.items[:q.head])
+		q.items, q.head = items, 0
+	}
+	q.items[(q.head+q.n)%len(q.items)] = v
+	q.n++
+}
+
+func (q *vertexQueue) pop() queuedVertex {
+	v := q.items[q.head]
+	// release the consumed slot
+	q.items[q.head] = queuedVertex{}
+	q.head = (q.head + 1) % len(q.items)
+	q.n--
+	return v
+}
+
+func (g *graph) traverseBFSWithDepth(edgeName string, s Vertex, maxDepth int, fn func(p Vertex, v []Vertex, depth int) bool) {
 	// track visited vertices
 	visited := map[Vertex]bool{}
-	// track vertices to visit
-	queue := list.New()
-	queue.PushBack(s)
+	// track vertices to visit and their depth
+	var queue vertexQueue
+	queue.push(queuedVertex{v: s})
 	// mark start vertex as visited
 	visited[s] = true
 
-	for queue.Len() > 0 {
-		qv := queue.Front()
+	for queue.len() > 0 {
+		qv := queue.pop()
 
-		// iterate through all children
-		children := g.m.Get(edgeName, qv.Value.(Vertex))
+		// vertices at max depth are yielded without children as these lie beyond the limit
+		var children []Vertex
+		if maxDepth < 0 || qv.depth < maxDepth {
+			children = g.m.Get(edgeName, qv.v)
+		}
 
-		if ok := fn(qv.Value.(Vertex), children); !ok {
+		if ok := fn(qv.v, children, qv.depth); !ok {
 			return
 		}
 
+		// iterate through all children
 		for _, child := range children {
 			if ok := visited[child]; !ok {
 				visited[child] = true
-				queue.PushBack(child)
+				queue.push(queuedVertex{v: child, depth: qv.depth + 1})
 			}
 		}
-
-		queue.Remove(qv)
 	}
 }
 
@@ -0,0 +1,51 @@
+/*
+Copyright © 2021 The OpenDependency Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package graph
+
+import (
+	"fmt"
+	"testing"
+)
+
+// newBenchmarkGraph creates a graph of n vertices where vertex i depends on vertices 2i+1 and 2i+2.
+func newBenchmarkGraph(n int) *graph {
+	m := NewInMemoryAdjacentMatrix()
+	vertex := func(i int) Vertex {
+		return Vertex{Namespace: "com.example", Name: fmt.Sprintf("module-%d", i), Type: "go", Version: "v1.0.0"}
+	}
+	for i := 0; i < n; i++ {
+		for _, c := range []int{2*i + 1, 2*i + 2} {
+			if c < n {
+				m.AddEdge(dependsOnEdge, vertex(i), vertex(c))
+			}
+		}
+	}
+	return NewGraph(m)
+}
+
+func BenchmarkTraverseBFS(b *testing.B) {
+	g := newBenchmarkGraph(100000)
+	s := Vertex{Namespace: "com.example", Name: "module-0", Type: "go", Version: "v1.0.0"}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		g.traverseBFS(dependsOnEdge, s, func(p Vertex, v []Vertex) bool {
+			return true
+		})
+	}
+}
@@ -17,6 +17,8 @@ limitations under the License.
 package graph
 
 import (
+	"fmt"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	spec "github.com/opendependency/go-spec/pkg/spec/v1"
@@ -34,6 +36,82 @@ var _ = Describe("graph", func() {
 		g = NewGraph(m)
 	})
 
+	Context("parse vertex", func() {
+
+		When("reference is valid", func() {
+			It("returns the vertex", func() {
+				v, err := ParseVertex("com.example:product:go:v1.0.0")
+
+				Expect(err).To(BeNil())
+				Expect(v).To(Equal(Vertex{Namespace: "com.example", Name: "product", Type: "go", Version: "v1.0.0"}))
+			})
+		})
+
+		for _, ref := range []string{"", "a:b:c", "a:b:c:d:e", "a::c:d"} {
+			ref := ref
+			When("reference is "+ref, func() {
+				It("returns an error", func() {
+					_, err := ParseVertex(ref)
+
+					Expect(err).To(MatchError(fmt.Sprintf("invalid module reference %q; expected <namespace>:<name>:<type>:<version>", ref)))
+				})
+			})
+		}
+
+		When("reference contains uppercase characters", func() {
+			It("returns an error", func() {
+				_, err := ParseVertex("com.example:Product:go:v1.0.0")
+
+				Expect(err).To(MatchError(`invalid module reference "com.example:Product:go:v1.0.0"; segment "Product" must be lowercase`))
+			})
+		})
+	})
+
+	Context("vertex queue", func() {
+		It("yields vertices in insertion order while growing after wrapping around", func() {
+			vertex := func(i int) queuedVertex {
+				return queuedVertex{v: Vertex{Namespace: "com.example", Name: fmt.Sprintf("module-%d", i)}, depth: i}
+			}
+
+			var q vertexQueue
+			var popped []int
+			for i := 0; i < 3; i++ {
+				q.push(vertex(i))
+			}
+			popped = append(popped, q.pop().depth, q.pop().depth)
+			for i := 3; i < 10; i++ {
+				q.push(vertex(i))
+			}
+			for q.len() > 0 {
+				qv := q.pop()
+				Expect(qv).To(Equal(vertex(qv.depth)))
+				popped = append(popped, qv.depth)
+			}
+
+			Expect(popped).To(Equal([]int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}))
+		})
+	})
+
+	Context("normalize reference", func() {
+
+		for _, tt := range []struct {
+			ref      string
+			expected string
+		}{
+			{ref: "com.example:product:go:v1.0.0", expected: "com.example:product:go:v1.0.0"},
+			{ref: " com.example : product :go:v1.0.0\n", expected: "com.example:product:go:v1.0.0"},
+			{ref: "product ", expected: "product"},
+			{ref: "com.example:Product:go:v1.0.0 ", expected: "com.example:Product:go:v1.0.0"},
+		} {
+			tt := tt
+			When(fmt.Sprintf("reference is %q", tt.ref), func() {
+				It("trims whitespace around segments", func() {
+					Expect(NormalizeRef(tt.ref)).To(Equal(tt.expected))
+				})
+			})
+		}
+	})
+
 	Context("add module", func() {
 
 		When("module is nil", func() {
@@ -243,6 +321,49 @@ var _ = Describe("graph", func() {
 
 	})
 
+	Context("metadata", func() {
+
+		BeforeEach(func() {
+			schema := "org.semver.v2"
+			err := g.AddModule(&spec.Module{
+				Namespace: "com.example",
+				Name:      "product",
+				Type:      "go",
+				Version: &spec.ModuleVersion{
+					Name:   "v1.0.0",
+					Schema: &schema,
+				},
+				Annotations: map[string]string{"team": "payments"},
+				Dependencies: []*spec.ModuleDependency{
+					{Namespace: "com.example", Name: "lib", Type: "go", Version: "v1.2.3"},
+					{Namespace: "com.example", Name: "time", Type: "go", Version: "v0.1.0"},
+				},
+			})
+			Expect(err).To(BeNil())
+		})
+
+		When("vertex was added as module", func() {
+			It("returns the module metadata", func() {
+				metadata, ok := g.Metadata(Vertex{Namespace: "com.example", Name: "product", Type: "go", Version: "v1.0.0"})
+
+				Expect(ok).To(BeTrue())
+				Expect(metadata).To(Equal(VertexMetadata{
+					Annotations:     map[string]string{"team": "payments"},
+					Schema:          "org.semver.v2",
+					DependencyCount: 2,
+				}))
+			})
+		})
+
+		When("vertex is only known as dependency", func() {
+			It("returns no metadata", func() {
+				_, ok := g.Metadata(Vertex{Namespace: "com.example", Name: "lib", Type: "go", Version: "v1.2.3"})
+
+				Expect(ok).To(BeFalse())
+			})
+		})
+	})
+
 	Context("traverse breadth first search", func() {
 		var (
 			startVertex Vertex
@@ -1048,5 +1169,297 @@ var _ = Describe("graph", func() {
 			})
 
 		})
+
+		Context("traverse depends-on edges bfs with depth", func() {
+
+			var (
+				parents  []Vertex
+				children [][]Vertex
+				depths   []int
+			)
+
+			traverse := func(maxDepth int) {
+				parents, children, depths = nil, nil, nil
+				g.TraverseDependOnEdgesBFSWithDepth(Vertex{Namespace: "com.example", Name: "order", Type: "helm", Version: "v2.3.8"}, maxDepth, func(p Vertex, v []Vertex, depth int) bool {
+					parents = append(parents, p)
+					children = append(children, v)
+					depths = append(depths, depth)
+					return true
+				})
+			}
+
+			When("max depth is 0", func() {
+				It("only yields the start vertex", func() {
+					traverse(0)
+
+					Expect(parents).To(Equal([]Vertex{
+						{Namespace: "com.example", Name: "order", Type: "helm", Version: "v2.3.8"},
+					}))
+					Expect(children).To(HaveLen(1))
+					Expect(children[0]).To(BeEmpty())
+					Expect(depths).To(Equal([]int{0}))
+				})
+			})
+
+			When("max depth is 1", func() {
+				It("only yields the start vertex and its direct children", func() {
+					traverse(1)
+
+					Expect(parents).To(Equal([]Vertex{
+						{Namespace: "com.example", Name: "order", Type: "helm", Version: "v2.3.8"},
+						{Namespace: "com.example", Name: "order", Type: "container-image", Version: "v2.3.8"},
+					}))
+					Expect(children).To(HaveLen(2))
+					Expect(children[0]).To(Equal([]Vertex{
+						{Namespace: "com.example", Name: "order", Type: "container-image", Version: "v2.3.8"},
+					}))
+					Expect(children[1]).To(BeEmpty())
+					Expect(depths).To(Equal([]int{0, 1}))
+				})
+			})
+
+			When("max depth is unlimited", func() {
+				It("yields all vertices with their depth", func() {
+					traverse(-1)
+
+					Expect(parents).To(HaveLen(5))
+					Expect(parents[:3]).To(Equal([]Vertex{
+						{Namespace: "com.example", Name: "order", Type: "helm", Version: "v2.3.8"},
+						{Namespace: "com.example", Name: "order", Type: "container-image", Version: "v2.3.8"},
+						{Namespace: "com.example", Name: "order", Type: "go", Version: "v2.3.8"},
+					}))
+					Expect(children[2]).To(ConsistOf(
+						Vertex{Namespace: "com.example", Name: "product", Type: "protobuf", Version: "v1.0.0"},
+						Vertex{Namespace: "com.example", Name: "utils", Type: "go", Version: "v4.3.1"},
+					))
+					Expect(depths).To(Equal([]int{0, 1, 2, 3, 3}))
+				})
+			})
+
+			When("max depth is below -1", func() {
+				It("yields all vertices like an unlimited max depth", func() {
+					traverse(-1)
+					unlimitedParents, unlimitedDepths := parents, depths
+
+					traverse(-2)
+
+					Expect(parents).To(ConsistOf(unlimitedParents))
+					Expect(depths).To(Equal(unlimitedDepths))
+				})
+			})
+
+			It("stops the traversal when fn returns false", func() {
+				var n int
+				g.TraverseDependOnEdgesBFSWithDepth(Vertex{Namespace: "com.example", Name: "order", Type: "helm", Version: "v2.3.8"}, -1, func(p Vertex, v []Vertex, depth int) bool {
+					n++
+					return false
+				})
+
+				Expect(n).To(Equal(1))
+			})
+		})
+
+		Context("shortest path", func() {
+
+			var (
+				orderHelm  = Vertex{Namespace: "com.example", Name: "order", Type: "helm", Version: "v2.3.8"}
+				orderImage = Vertex{Namespace: "com.example", Name: "order", Type: "container-image", Version: "v2.3.8"}
+				orderGo    = Vertex{Namespace: "com.example", Name: "order", Type: "go", Version: "v2.3.8"}
+				utilsGo    = Vertex{Namespace: "com.example", Name: "utils", Type: "go", Version: "v4.3.1"}
+				productGo  = Vertex{Namespace: "com.example", Name: "product", Type: "go", Version: "v1.5.0"}
+			)
+
+			It("returns the path of a direct edge", func() {
+				path, ok := g.ShortestPath(DependsOnEdgeKind, orderHelm, orderImage)
+				Expect(ok).To(BeTrue())
+				Expect(path).To(Equal([]Vertex{orderHelm, orderImage}))
+			})
+
+			It("returns the path over multiple hops", func() {
+				path, ok := g.ShortestPath(DependsOnEdgeKind, orderHelm, utilsGo)
+				Expect(ok).To(BeTrue())
+				Expect(path).To(Equal([]Vertex{orderHelm, orderImage, orderGo, utilsGo}))
+			})
+
+			It("returns the path over the given edge kind", func() {
+				path, ok := g.ShortestPath(UsedByEdgeKind, utilsGo, orderHelm)
+				Expect(ok).To(BeTrue())
+				Expect(path).To(Equal([]Vertex{utilsGo, orderGo, orderImage, orderHelm}))
+			})
+
+			It("returns the start vertex if both vertices are equal", func() {
+				path, ok := g.ShortestPath(DependsOnEdgeKind, orderHelm, orderHelm)
+				Expect(ok).To(BeTrue())
+				Expect(path).To(Equal([]Vertex{orderHelm}))
+			})
+
+			It("returns false for an unreachable pair", func() {
+				path, ok := g.ShortestPath(DependsOnEdgeKind, orderHelm, productGo)
+				Expect(ok).To(BeFalse())
+				Expect(path).To(BeNil())
+
+				path, ok = g.ShortestPath(DependsOnEdgeKind, utilsGo, orderHelm)
+				Expect(ok).To(BeFalse())
+				Expect(path).To(BeNil())
+			})
+		})
+
+		Context("collect depends-on", func() {
+			It("returns all reachable vertices in breadth-first order", func() {
+				vertices := g.CollectDependOn(Vertex{Namespace: "com.example", Name: "order", Type: "helm", Version: "v2.3.8"})
+
+				Expect(vertices).To(HaveLen(4))
+				Expect(vertices[:2]).To(Equal([]Vertex{
+					{Namespace: "com.example", Name: "order", Type: "container-image", Version: "v2.3.8"},
+					{Namespace: "com.example", Name: "order", Type: "go", Version: "v2.3.8"},
+				}))
+				Expect(vertices[2:]).To(ConsistOf(
+					Vertex{Namespace: "com.example", Name: "product", Type: "protobuf", Version: "v1.0.0"},
+					Vertex{Namespace: "com.example", Name: "utils", Type: "go", Version: "v4.3.1"},
+				))
+			})
+
+			It("returns no vertices for a vertex without edges", func() {
+				vertices := g.CollectDependOn(Vertex{Namespace: "com.example", Name: "utils", Type: "go", Version: "v4.3.1"})
+
+				Expect(vertices).To(BeEmpty())
+			})
+		})
+
+		Context("collect depends-on tree", func() {
+			It("returns the adjacency of all reachable vertices", func() {
+				tree := g.CollectDependOnTree(Vertex{Namespace: "com.example", Name: "order", Type: "helm", Version: "v2.3.8"})
+
+				Expect(tree).To(HaveLen(3))
+				Expect(tree[Vertex{Namespace: "com.example", Name: "order", Type: "helm", Version: "v2.3.8"}]).To(ConsistOf(
+					Vertex{Namespace: "com.example", Name: "order", Type: "container-image", Version: "v2.3.8"},
+				))
+				Expect(tree[Vertex{Namespace: "com.example", Name: "order", Type: "container-image", Version: "v2.3.8"}]).To(ConsistOf(
+					Vertex{Namespace: "com.example", Name: "order", Type: "go", Version: "v2.3.8"},
+				))
+				Expect(tree[Vertex{Namespace: "com.example", Name: "order", Type: "go", Version: "v2.3.8"}]).To(ConsistOf(
+					Vertex{Namespace: "com.example", Name: "product", Type: "protobuf", Version: "v1.0.0"},
+					Vertex{Namespace: "com.example", Name: "utils", Type: "go", Version: "v4.3.1"},
+				))
+			})
+
+			It("returns children which do not alias the graph", func() {
+				s := Vertex{Namespace: "com.example", Name: "order", Type: "helm", Version: "v2.3.8"}
+
+				tree := g.CollectDependOnTree(s)
+				tree[s][0] = Vertex{Namespace: "com.example", Name: "modified", Type: "go", Version: "v0.0.0"}
+				tree[s] = append(tree[s], Vertex{Namespace: "com.example", Name: "appended", Type: "go", Version: "v0.0.0"})
+
+				Expect(g.CollectDependOnTree(s)[s]).To(Equal([]Vertex{
+					{Namespace: "com.example", Name: "order", Type: "container-image", Version: "v2.3.8"},
+				}))
+				Expect(g.CollectDependOn(s)).To(HaveLen(4))
+			})
+		})
+
+		Context("collect used-by", func() {
+			It("returns all reachable vertices in breadth-first order", func() {
+				vertices := g.CollectUsedBy(Vertex{Namespace: "com.example", Name: "product", Type: "protobuf", Version: "v1.0.0"})
+
+				Expect(vertices).To(Equal([]Vertex{
+					{Namespace: "com.example", Name: "order", Type: "go", Version: "v2.3.8"},
+					{Namespace: "com.example", Name: "order", Type: "container-image", Version: "v2.3.8"},
+					{Namespace: "com.example", Name: "order", Type: "helm", Version: "v2.3.8"},
+				}))
+			})
+		})
+
+		Context("collect required-for and require", func() {
+			It("returns all reachable vertices", func() {
+				Expect(g.CollectRequiredFor(Vertex{Namespace: "com.example", Name: "product", Type: "go", Version: "v1.5.0"})).To(Equal([]Vertex{
+					{Namespace: "com.example", Name: "product", Type: "protobuf", Version: "v1.0.0"},
+				}))
+				Expect(g.CollectRequire(Vertex{Namespace: "com.example", Name: "product", Type: "protobuf", Version: "v1.0.0"})).To(Equal([]Vertex{
+					{Namespace: "com.example", Name: "product", Type: "go", Version: "v1.5.0"},
+				}))
+			})
+		})
+	})
+
+	Context("find cycles", func() {
+
+		var (
+			helm  Vertex
+			image Vertex
+			gomod Vertex
+		)
+
+		addModule := func(p Vertex, dependencies ...Vertex) {
+			module := &spec.Module{
+				Namespace: p.Namespace,
+				Name:      p.Name,
+				Type:      p.Type,
+				Version:   &spec.ModuleVersion{Name: p.Version},
+			}
+			for _, d := range dependencies {
+				module.Dependencies = append(module.Dependencies, &spec.ModuleDependency{
+					Namespace: d.Namespace,
+					Name:      d.Name,
+					Type:      d.Type,
+					Version:   d.Version,
+				})
+			}
+			if err := g.AddModule(module); err != nil {
+				Fail(err.Error())
+			}
+		}
+
+		BeforeEach(func() {
+			helm = Vertex{Namespace: "com.example", Name: "product", Type: "helm", Version: "v1.5.0"}
+			image = Vertex{Namespace: "com.example", Name: "product", Type: "container-image", Version: "v1.5.0"}
+			gomod = Vertex{Namespace: "com.example", Name: "product", Type: "go", Version: "v1.5.0"}
+		})
+
+		When("graph is acyclic", func() {
+			It("returns no cycles", func() {
+				addModule(helm, image)
+				addModule(image, gomod)
+
+				Expect(g.FindCycles(helm)).To(BeEmpty())
+			})
+		})
+
+		When("vertex depends on itself", func() {
+			It("returns a self-loop cycle", func() {
+				addModule(helm, image)
+				addModule(image, image)
+
+				Expect(g.FindCycles(helm)).To(Equal([][]Vertex{{image}}))
+			})
+		})
+
+		When("vertices form a cycle across types", func() {
+			It("returns the cycle in edge order", func() {
+				addModule(helm, image)
+				addModule(image, gomod)
+				addModule(gomod, helm)
+
+				Expect(g.FindCycles(helm)).To(Equal([][]Vertex{{helm, image, gomod}}))
+			})
+		})
+
+		When("vertices form multiple cycles", func() {
+			It("returns each cycle", func() {
+				addModule(helm, image, gomod)
+				addModule(image, helm)
+				addModule(gomod, gomod)
+
+				Expect(g.FindCycles(helm)).To(Equal([][]Vertex{{helm, image}, {gomod}}))
+			})
+		})
+
+		When("cycle is not reachable from start vertex", func() {
+			It("returns no cycles", func() {
+				addModule(helm, image)
+				addModule(gomod, gomod)
+
+				Expect(g.FindCycles(helm)).To(BeEmpty())
+			})
+		})
 	})
 })
@@ -41,7 +41,7 @@ func (a *inMemoryAdjacentMatrix) AddEdge(name string, p Vertex, c Vertex) {
 		matrix = map[Vertex][]Vertex{}
 		a.m[name] = matrix
 	}
-	matrix[p] = append(matrix[p], c)
+	matrix[p] = appendIfMissing(matrix[p], c)
 	a.mux.Unlock()
 }
 
@@ -52,10 +52,24 @@ func (a *inMemoryAdjacentMatrix) AddEdges(name string, p Vertex, c []Vertex) {
 		matrix = map[Vertex][]Vertex{}
 		a.m[name] = matrix
 	}
-	matrix[p] = append(matrix[p], c...)
+	children := matrix[p]
+	for _, v := range c {
+		children = appendIfMissing(children, v)
+	}
+	matrix[p] = children
 	a.mux.Unlock()
 }
 
+// appendIfMissing appends vertex v to vertices if not already contained.
+func appendIfMissing(vertices []Vertex, v Vertex) []Vertex {
+	for _, e := range vertices {
+		if e == v {
+			return vertices
+		}
+	}
+	return append(vertices, v)
+}
+
 func (a *inMemoryAdjacentMatrix) Get(name string, v Vertex) []Vertex {
 	a.mux.RLock()
 	defer a.mux.RUnlock()
@@ -66,6 +80,50 @@ func (a *inMemoryAdjacentMatrix) Get(name string, v Vertex) []Vertex {
 	return matrix[v]
 }
 
+func (a *inMemoryAdjacentMatrix) Parents(name string) []Vertex {
+	a.mux.RLock()
+	defer a.mux.RUnlock()
+	matrix, ok := a.m[name]
+	if !ok {
+		return nil
+	}
+	parents := make([]Vertex, 0, len(matrix))
+	for p := range matrix {
+		parents = append(parents, p)
+	}
+	return parents
+}
+
+func (a *inMemoryAdjacentMatrix) RemoveVertex(v Vertex) {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+	for _, matrix := range a.m {
+		delete(matrix, v)
+		for p, children := range matrix {
+			children = removeVertex(children, v)
+			// a parent without children has no edges left, like a parent never added
+			if len(children) == 0 {
+				delete(matrix, p)
+				continue
+			}
+			matrix[p] = children
+		}
+	}
+}
+
+// removeVertex returns vertices without vertex v.
+// A new slice is allocated if v is contained, as vertices may still be referenced by callers of Get.
+func removeVertex(vertices []Vertex, v Vertex) []Vertex {
+	for i, e := range vertices {
+		if e == v {
+			filtered := make([]Vertex, 0, len(vertices)-1)
+			filtered = append(filtered, vertices[:i]...)
+			return append(filtered, vertices[i+1:]...)
+		}
+	}
+	return vertices
+}
+
 func (a *inMemoryAdjacentMatrix) NumberOfEdges(name string) int {
 	return len(a.m[name])
 }
@@ -71,6 +71,15 @@ var _ = Describe("in-memory adjacent matrix", func() {
 				Expect(matrix.m["upstream"][Vertex{"a", "b", "c", "d"}]).To(HaveLen(1))
 			})
 		})
+
+		When("edge already exists", func() {
+			It("does not add a duplicate edge", func() {
+				matrix.AddEdge("upstream", Vertex{"a", "b", "c", "d"}, Vertex{"e", "f", "g", "h"})
+				matrix.AddEdge("upstream", Vertex{"a", "b", "c", "d"}, Vertex{"e", "f", "g", "h"})
+
+				Expect(matrix.Get("upstream", Vertex{"a", "b", "c", "d"})).To(Equal([]Vertex{{"e", "f", "g", "h"}}))
+			})
+		})
 	})
 
 	Context("add edges", func() {
@@ -113,6 +122,16 @@ var _ = Describe("in-memory adjacent matrix", func() {
 				Expect(matrix.m["upstream"][Vertex{"a", "b", "c", "d"}]).To(HaveLen(0))
 			})
 		})
+
+		When("child vertices overlap with existing edges", func() {
+			It("does not add duplicate edges", func() {
+				matrix.AddEdge("upstream", Vertex{"a", "b", "c", "d"}, Vertex{"e", "f", "g", "h"})
+				matrix.AddEdges("upstream", Vertex{"a", "b", "c", "d"}, []Vertex{{"e", "f", "g", "h"}, {"i", "j", "k", "l"}})
+				matrix.AddEdges("upstream", Vertex{"a", "b", "c", "d"}, []Vertex{{"i", "j", "k", "l"}, {"m", "n", "o", "p"}, {"m", "n", "o", "p"}})
+
+				Expect(matrix.Get("upstream", Vertex{"a", "b", "c", "d"})).To(Equal([]Vertex{{"e", "f", "g", "h"}, {"i", "j", "k", "l"}, {"m", "n", "o", "p"}}))
+			})
+		})
 	})
 
 	Context("get", func() {
@@ -173,6 +192,90 @@ var _ = Describe("in-memory adjacent matrix", func() {
 		})
 	})
 
+	Context("parents", func() {
+
+		When("named edge does not exist", func() {
+			It("returns nil", func() {
+				Expect(matrix.Parents("upstream")).To(BeNil())
+			})
+		})
+
+		When("named edge exists", func() {
+			It("returns all parent vertices", func() {
+				matrix.AddEdges("upstream", Vertex{"a", "b", "c", "d"}, []Vertex{{"e", "f", "g", "h"}})
+				matrix.AddEdges("upstream", Vertex{"e", "f", "g", "h"}, []Vertex{{"i", "j", "k", "l"}})
+				matrix.AddEdges("downstream", Vertex{"i", "j", "k", "l"}, []Vertex{{"a", "b", "c", "d"}})
+
+				Expect(matrix.Parents("upstream")).To(ConsistOf(Vertex{"a", "b", "c", "d"}, Vertex{"e", "f", "g", "h"}))
+			})
+		})
+	})
+
+	Context("remove vertex", func() {
+
+		BeforeEach(func() {
+			matrix.AddEdges("upstream", Vertex{"a", "b", "c", "d"}, []Vertex{{"e", "f", "g", "h"}, {"i", "j", "k", "l"}})
+			matrix.AddEdges("upstream", Vertex{"e", "f", "g", "h"}, []Vertex{{"i", "j", "k", "l"}})
+			matrix.AddEdges("downstream", Vertex{"e", "f", "g", "h"}, []Vertex{{"a", "b", "c", "d"}})
+			matrix.AddEdges("downstream", Vertex{"i", "j", "k", "l"}, []Vertex{{"a", "b", "c", "d"}, {"e", "f", "g", "h"}})
+		})
+
+		It("removes the vertex as parent and as child from all named edges", func() {
+			matrix.RemoveVertex(Vertex{"e", "f", "g", "h"})
+
+			Expect(matrix.Get("upstream", Vertex{"a", "b", "c", "d"})).To(Equal([]Vertex{{"i", "j", "k", "l"}}))
+			Expect(matrix.Get("upstream", Vertex{"e", "f", "g", "h"})).To(BeNil())
+			Expect(matrix.Get("downstream", Vertex{"e", "f", "g", "h"})).To(BeNil())
+			Expect(matrix.Get("downstream", Vertex{"i", "j", "k", "l"})).To(Equal([]Vertex{{"a", "b", "c", "d"}}))
+			Expect(matrix.NumberOfEdges("upstream")).To(Equal(1))
+			Expect(matrix.NumberOfEdges("downstream")).To(Equal(1))
+		})
+
+		It("removes parents left without children", func() {
+			matrix.AddEdges("upstream", Vertex{"x", "y", "z", "w"}, []Vertex{{"e", "f", "g", "h"}})
+
+			matrix.RemoveVertex(Vertex{"e", "f", "g", "h"})
+
+			Expect(matrix.Get("upstream", Vertex{"x", "y", "z", "w"})).To(BeNil())
+			Expect(matrix.Parents("upstream")).To(ConsistOf(Vertex{"a", "b", "c", "d"}))
+			Expect(matrix.NumberOfEdges("upstream")).To(Equal(1))
+		})
+
+		It("equals a matrix built without the vertex", func() {
+			matrix.AddEdges("upstream", Vertex{"x", "y", "z", "w"}, []Vertex{{"e", "f", "g", "h"}})
+
+			matrix.RemoveVertex(Vertex{"e", "f", "g", "h"})
+
+			expected := NewInMemoryAdjacentMatrix()
+			expected.AddEdges("upstream", Vertex{"a", "b", "c", "d"}, []Vertex{{"i", "j", "k", "l"}})
+			expected.AddEdges("downstream", Vertex{"i", "j", "k", "l"}, []Vertex{{"a", "b", "c", "d"}})
+
+			for _, name := range []string{"upstream", "downstream"} {
+				Expect(matrix.Parents(name)).To(ConsistOf(expected.Parents(name)))
+				Expect(matrix.NumberOfEdges(name)).To(Equal(expected.NumberOfEdges(name)))
+				for _, p := range expected.Parents(name) {
+					Expect(matrix.Get(name, p)).To(Equal(expected.Get(name, p)))
+				}
+			}
+			Expect(matrix.m).To(Equal(expected.m))
+		})
+
+		It("does not modify previously returned vertices", func() {
+			children := matrix.Get("upstream", Vertex{"a", "b", "c", "d"})
+
+			matrix.RemoveVertex(Vertex{"e", "f", "g", "h"})
+
+			Expect(children).To(Equal([]Vertex{{"e", "f", "g", "h"}, {"i", "j", "k", "l"}}))
+		})
+
+		It("ignores an unknown vertex", func() {
+			matrix.RemoveVertex(Vertex{"x", "y", "z", "w"})
+
+			Expect(matrix.NumberOfEdges("upstream")).To(Equal(2))
+			Expect(matrix.NumberOfEdges("downstream")).To(Equal(2))
+		})
+	})
+
 	Context("number of edges", func() {
 
 		When("matrix is empty", func() {
@@ -0,0 +1,166 @@
+/*
+Copyright © 2021 The OpenDependency Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package graph
+
+import (
+	"errors"
+	"io"
+	"sync/atomic"
+)
+
+// SafeGraph represents a read-only graph whose underlying graph can be replaced atomically
+// while it is read concurrently. Modules are added by building a new graph and replacing
+// the current one, as modifying a graph would be visible to concurrent readers.
+type SafeGraph interface {
+	ReadOnlyGraph
+	// Replace replaces the current graph with the given fully built graph.
+	// Reads started before the replacement continue on the previous graph.
+	// The given graph must not be modified afterwards.
+	Replace(g ReadOnlyGraph) error
+}
+
+// NewSafeGraph creates a new safe graph with the given graph as initial snapshot.
+func NewSafeGraph(g ReadOnlyGraph) (*safeGraph, error) {
+	s := &safeGraph{}
+	if err := s.Replace(g); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+var _ SafeGraph = (*safeGraph)(nil)
+
+type safeGraph struct {
+	// v holds a graphSnapshot, as atomic.Value requires a consistent concrete type.
+	v atomic.Value
+}
+
+type graphSnapshot struct {
+	g ReadOnlyGraph
+}
+
+func (s *safeGraph) Replace(g ReadOnlyGraph) error {
+	if g == nil {
+		return errors.New("graph must not be nil")
+	}
+	s.v.Store(graphSnapshot{g: g})
+	return nil
+}
+
+func (s *safeGraph) current() ReadOnlyGraph {
+	return s.v.Load().(graphSnapshot).g
+}
+
+func (s *safeGraph) Metadata(v Vertex) (VertexMetadata, bool) {
+	return s.current().Metadata(v)
+}
+
+func (s *safeGraph) TraverseDependOnEdgesBFS(v Vertex, fn func(p Vertex, v []Vertex) bool) {
+	s.current().TraverseDependOnEdgesBFS(v, fn)
+}
+
+func (s *safeGraph) TraverseDependOnEdgesDFS(v Vertex, fn func(p Vertex, v Vertex) bool) {
+	s.current().TraverseDependOnEdgesDFS(v, fn)
+}
+
+func (s *safeGraph) TraverseUsedByEdgesBFS(v Vertex, fn func(p Vertex, v []Vertex) bool) {
+	s.current().TraverseUsedByEdgesBFS(v, fn)
+}
+
+func (s *safeGraph) TraverseUsedByEdgesDFS(v Vertex, fn func(p Vertex, v Vertex) bool) {
+	s.current().TraverseUsedByEdgesDFS(v, fn)
+}
+
+func (s *safeGraph) TraverseRequiredForEdgesBFS(v Vertex, fn func(p Vertex, v []Vertex) bool) {
+	s.current().TraverseRequiredForEdgesBFS(v, fn)
+}
+
+func (s *safeGraph) TraverseRequiredForEdgesDFS(v Vertex, fn func(p Vertex, v Vertex) bool) {
+	s.current().TraverseRequiredForEdgesDFS(v, fn)
+}
+
+func (s *safeGraph) TraverseRequireEdgesBFS(v Vertex, fn func(p Vertex, v []Vertex) bool) {
+	s.current().TraverseRequireEdgesBFS(v, fn)
+}
+
+func (s *safeGraph) TraverseRequireEdgesDFS(v Vertex, fn func(p Vertex, v Vertex) bool) {
+	s.current().TraverseRequireEdgesDFS(v, fn)
+}
+
+func (s *safeGraph) TraverseDependOnEdgesBFSWithDepth(v Vertex, maxDepth int, fn func(p Vertex, v []Vertex, depth int) bool) {
+	s.current().TraverseDependOnEdgesBFSWithDepth(v, maxDepth, fn)
+}
+
+func (s *safeGraph) TraverseUsedByEdgesBFSWithDepth(v Vertex, maxDepth int, fn func(p Vertex, v []Vertex, depth int) bool) {
+	s.current().TraverseUsedByEdgesBFSWithDepth(v, maxDepth, fn)
+}
+
+func (s *safeGraph) TraverseRequiredForEdgesBFSWithDepth(v Vertex, maxDepth int, fn func(p Vertex, v []Vertex, depth int) bool) {
+	s.current().TraverseRequiredForEdgesBFSWithDepth(v, maxDepth, fn)
+}
+
+func (s *safeGraph) TraverseRequireEdgesBFSWithDepth(v Vertex, maxDepth int, fn func(p Vertex, v []Vertex, depth int) bool) {
+	s.current().TraverseRequireEdgesBFSWithDepth(v, maxDepth, fn)
+}
+
+func (s *safeGraph) CollectDependOn(v Vertex) []Vertex {
+	return s.current().CollectDependOn(v)
+}
+
+func (s *safeGraph) CollectDependOnTree(v Vertex) map[Vertex][]Vertex {
+	return s.current().CollectDependOnTree(v)
+}
+
+func (s *safeGraph) CollectUsedBy(v Vertex) []Vertex {
+	return s.current().CollectUsedBy(v)
+}
+
+func (s *safeGraph) CollectUsedByTree(v Vertex) map[Vertex][]Vertex {
+	return s.current().CollectUsedByTree(v)
+}
+
+func (s *safeGraph) CollectRequiredFor(v Vertex) []Vertex {
+	return s.current().CollectRequiredFor(v)
+}
+
+func (s *safeGraph) CollectRequiredForTree(v Vertex) map[Vertex][]Vertex {
+	return s.current().CollectRequiredForTree(v)
+}
+
+func (s *safeGraph) CollectRequire(v Vertex) []Vertex {
+	return s.current().CollectRequire(v)
+}
+
+func (s *safeGraph) CollectRequireTree(v Vertex) map[Vertex][]Vertex {
+	return s.current().CollectRequireTree(v)
+}
+
+func (s *safeGraph) FindCycles(v Vertex) [][]Vertex {
+	return s.current().FindCycles(v)
+}
+
+func (s *safeGraph) ToDOT(w io.Writer) error {
+	return s.current().ToDOT(w)
+}
+
+func (s *safeGraph) ToMermaid(w io.Writer) error {
+	return s.current().ToMermaid(w)
+}
+
+func (s *safeGraph) ShortestPath(kind EdgeKind, from Vertex, to Vertex) ([]Vertex, bool) {
+	return s.current().ShortestPath(kind, from, to)
+}
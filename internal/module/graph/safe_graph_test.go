@@ -0,0 +1,183 @@
+/*
+Copyright © 2021 The OpenDependency Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package graph
+
+import (
+	"sync"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/types"
+	spec "github.com/opendependency/go-spec/pkg/spec/v1"
+)
+
+var _ = Describe("safe graph", func() {
+
+	var (
+		startVertex Vertex
+		first       Graph
+		second      Graph
+		s           *safeGraph
+	)
+
+	newChainGraph := func(names ...string) Graph {
+		g := NewGraph(NewInMemoryAdjacentMatrix())
+
+		parent := "product"
+		for _, name := range names {
+			err := g.AddModule(&spec.Module{
+				Namespace: "com.example",
+				Name:      parent,
+				Type:      "go",
+				Version:   &spec.ModuleVersion{Name: "v1.0.0"},
+				Dependencies: []*spec.ModuleDependency{
+					{
+						Namespace: "com.example",
+						Name:      name,
+						Type:      "go",
+						Version:   "v1.0.0",
+					},
+				},
+			})
+			if err != nil {
+				Fail(err.Error())
+			}
+			parent = name
+		}
+
+		return g
+	}
+
+	collectDependOn := func(g ReadOnlyGraph) []Vertex {
+		var vertices []Vertex
+		g.TraverseDependOnEdgesDFS(startVertex, func(p Vertex, v Vertex) bool {
+			vertices = append(vertices, v)
+			return true
+		})
+		return vertices
+	}
+
+	BeforeEach(func() {
+		startVertex = Vertex{Namespace: "com.example", Name: "product", Type: "go", Version: "v1.0.0"}
+		first = newChainGraph("lib")
+		second = newChainGraph("util", "time", "pricing")
+
+		var err error
+		s, err = NewSafeGraph(first)
+		Expect(err).To(BeNil())
+	})
+
+	Context("new safe graph", func() {
+
+		When("graph is nil", func() {
+			It("returns an error", func() {
+				s, err := NewSafeGraph(nil)
+
+				Expect(err).ToNot(BeNil())
+				Expect(s).To(BeNil())
+			})
+		})
+	})
+
+	Context("replace", func() {
+
+		It("delegates reads to the initial graph", func() {
+			Expect(collectDependOn(s)).To(Equal(collectDependOn(first)))
+		})
+
+		It("delegates reads to the replaced graph", func() {
+			Expect(s.Replace(second)).To(BeNil())
+
+			Expect(collectDependOn(s)).To(Equal(collectDependOn(second)))
+		})
+
+		When("graph is nil", func() {
+			It("returns an error and keeps the current graph", func() {
+				Expect(s.Replace(nil)).ToNot(BeNil())
+
+				Expect(collectDependOn(s)).To(Equal(collectDependOn(first)))
+			})
+		})
+
+		It("is read-only", func() {
+			_, ok := interface{}(s).(Graph)
+
+			Expect(ok).To(BeFalse())
+		})
+
+		It("never exposes partial state to concurrent readers and keeps the last replacement", func() {
+			writerGraphs := []Graph{
+				second,
+				newChainGraph("cart"),
+				newChainGraph("stock", "warehouse"),
+				newChainGraph("billing", "invoice", "tax", "currency"),
+			}
+			known := []types.GomegaMatcher{Equal(collectDependOn(first))}
+			writerMatchers := make([]types.GomegaMatcher, 0, len(writerGraphs))
+			for _, g := range writerGraphs {
+				writerMatchers = append(writerMatchers, Equal(collectDependOn(g)))
+			}
+			known = append(known, writerMatchers...)
+
+			var readers, writers sync.WaitGroup
+			observed := make(chan []Vertex, 800)
+			errs := make(chan error, 400)
+
+			for i := 0; i < 8; i++ {
+				readers.Add(1)
+				go func() {
+					defer readers.Done()
+					for j := 0; j < 100; j++ {
+						observed <- collectDependOn(s)
+					}
+				}()
+			}
+
+			for _, g := range writerGraphs {
+				writers.Add(1)
+				go func(g Graph) {
+					defer writers.Done()
+					for j := 0; j < 100; j++ {
+						if err := s.Replace(g); err != nil {
+							errs <- err
+						}
+					}
+				}(g)
+			}
+
+			readers.Wait()
+			writers.Wait()
+			close(observed)
+			close(errs)
+
+			for err := range errs {
+				Expect(err).To(BeNil())
+			}
+
+			Expect(observed).To(HaveLen(800))
+			for vertices := range observed {
+				Expect(vertices).To(Or(known...))
+			}
+
+			// one of the writers replaced the graph last
+			Expect(collectDependOn(s)).To(Or(writerMatchers...))
+
+			Expect(s.Replace(first)).To(BeNil())
+			Expect(collectDependOn(s)).To(Equal(collectDependOn(first)))
+		})
+	})
+})
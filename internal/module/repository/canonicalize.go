@@ -0,0 +1,49 @@
+/*
+Copyright © 2021 The OpenDependency Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repository
+
+import (
+	"sort"
+
+	spec "github.com/opendependency/go-spec/pkg/spec/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// canonicalizeModule returns a copy of the given module with its dependencies
+// sorted by namespace, name, type, version and direction.
+func canonicalizeModule(module *spec.Module) *spec.Module {
+	clone := proto.Clone(module).(*spec.Module)
+
+	sort.SliceStable(clone.Dependencies, func(i, j int) bool {
+		a, b := clone.Dependencies[i], clone.Dependencies[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		if a.Version != b.Version {
+			return a.Version < b.Version
+		}
+		return a.GetDirection() < b.GetDirection()
+	})
+
+	return clone
+}
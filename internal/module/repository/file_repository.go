@@ -18,13 +18,18 @@ package repository
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/gofrs/flock"
@@ -34,29 +39,65 @@ import (
 
 const (
 	modulesDirectory    = "modules"
+	objectsDirectory    = "objects"
 	moduleFileExtension = "module.bin"
+	// tombstoneFileExtension marks a module version as deleted.
+	tombstoneFileExtension = "module.deleted"
+	// deletedMarkerFile marks a directory which only consists of deleted module versions.
+	// Its name cannot collide with module coordinates, which must not contain underscores.
+	deletedMarkerFile = "_deleted"
+	// maxConcurrentReads limits the number of module files read in parallel.
+	maxConcurrentReads = 8
 )
 
 // NewFileRepository creates a new file repository under the given path.
-func NewFileRepository(path string) (*fileRepository, error) {
+func NewFileRepository(path string, opts ...Option) (*fileRepository, error) {
+	o := newOptions(opts)
+
 	absDir, err := filepath.Abs(filepath.Join(path, modulesDirectory))
 	if err != nil {
 		return nil, fmt.Errorf("could not get absolute path: %w", err)
 	}
 
-	if err := os.MkdirAll(absDir, os.ModePerm); err != nil && !os.IsExist(err) {
-		return nil, fmt.Errorf("could not create directory: %w", err)
+	absObjectsDir, err := filepath.Abs(filepath.Join(path, objectsDirectory))
+	if err != nil {
+		return nil, fmt.Errorf("could not get absolute path: %w", err)
 	}
 
-	return &fileRepository{
-		path: absDir,
-	}, nil
+	if o.createDirectory {
+		if err := os.MkdirAll(absDir, os.ModePerm); err != nil && !os.IsExist(err) {
+			return nil, fmt.Errorf("could not create directory: %w", err)
+		}
+	} else if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, errors.New("repository directory does not exist")
+	}
+
+	r := &fileRepository{
+		opts:            o,
+		path:            absDir,
+		objectsPath:     absObjectsDir,
+		removeDirectory: os.Remove,
+	}
+	if o.writeQueue {
+		r.writeQueue = newWriteQueue()
+	}
+
+	return r, nil
 }
 
 var _ Repository = (*fileRepository)(nil)
+var _ Compactable = (*fileRepository)(nil)
+var _ Touchable = (*fileRepository)(nil)
 
 type fileRepository struct {
+	opts options
 	path string
+	// objectsPath is only used by the content-addressed layout.
+	objectsPath string
+	// writeQueue is nil unless writes are serialized in-process.
+	writeQueue *writeQueue
+	// removeDirectory removes an empty directory, tests replace it to interleave concurrent writes.
+	removeDirectory func(name string) error
 }
 
 func (r *fileRepository) AddModule(module *spec.Module) (rerr error) {
@@ -68,24 +109,25 @@ func (r *fileRepository) AddModule(module *spec.Module) (rerr error) {
 		return fmt.Errorf("module validation failed: %w", err)
 	}
 
-	serializedModule, err := proto.Marshal(module)
-	if err != nil {
-		return fmt.Errorf("could not marhsal proto: %w", err)
+	if r.opts.canonicalize {
+		module = canonicalizeModule(module)
 	}
 
-	if err := os.MkdirAll(r.getAbsoluteModuleTypeDirectoryPath(module.Namespace, module.Name, module.Type), os.ModePerm); err != nil && !os.IsExist(err) {
-		return fmt.Errorf("could not create directory: %w", err)
+	serializedModule, err := proto.MarshalOptions{Deterministic: r.opts.canonicalize}.Marshal(module)
+	if err != nil {
+		return fmt.Errorf("could not marhsal proto: %w", err)
 	}
 
 	targetAbsModuleFilePath := r.getAbsoluteModuleFilePath(module.Namespace, module.Name, module.Type, module.Version.Name)
 
-	l := r.newFileLock(targetAbsModuleFilePath)
-	lockCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+	if r.writeQueue != nil {
+		release := r.writeQueue.acquire(r.getAbsoluteModuleTypeDirectoryPath(module.Namespace, module.Name, module.Type))
+		defer release()
+	}
 
-	locked, err := l.TryLockContext(lockCtx, 500*time.Millisecond)
-	if !locked || err != nil {
-		return fmt.Errorf("could not lock: %s", l.Path())
+	l, err := r.lockNewModuleFile(targetAbsModuleFilePath)
+	if err != nil {
+		return err
 	}
 
 	defer func() {
@@ -97,17 +139,217 @@ func (r *fileRepository) AddModule(module *spec.Module) (rerr error) {
 		}
 	}()
 
-	if err := ioutil.WriteFile(targetAbsModuleFilePath, serializedModule, os.ModePerm); err != nil {
+	content := serializedModule
+	if r.opts.layout == ContentAddressed {
+		// hold the objects lock until the pointer is written, so that compaction never collects the object in between
+		ol, err := r.lockObjects(false)
+		if err != nil {
+			return err
+		}
+		defer ol.Unlock()
+
+		object, err := marshalObject(module)
+		if err != nil {
+			return err
+		}
+		digest, err := r.writeObject(object)
+		if err != nil {
+			return err
+		}
+		content = []byte(digest)
+	}
+
+	if err := ioutil.WriteFile(targetAbsModuleFilePath, content, os.ModePerm); err != nil {
 		return fmt.Errorf("could not write module file: %w", err)
 	}
 
-	return nil
+	if err := os.Remove(r.getAbsoluteTombstoneFilePath(targetAbsModuleFilePath)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("could not remove tombstone file: %w", err)
+	}
+
+	return r.removeDeletedMarkers(module.Namespace, module.Name, module.Type)
+}
+
+// lockNewModuleFile creates the directory of the given module file and locks the module file.
+// Deletes and Compact remove empty directories and stale lock files, therefore locking is retried
+// if the directory vanished meanwhile or the locked file is no longer the lock file at its path.
+func (r *fileRepository) lockNewModuleFile(absModuleFilePath string) (*flock.Flock, error) {
+	lockCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	for {
+		if err := os.MkdirAll(filepath.Dir(absModuleFilePath), os.ModePerm); err != nil && !os.IsExist(err) {
+			return nil, fmt.Errorf("could not create directory: %w", err)
+		}
+
+		l := r.newFileLock(absModuleFilePath)
+		f, err := os.OpenFile(l.Path(), os.O_CREATE|os.O_RDONLY, os.ModePerm)
+		if os.IsNotExist(err) && lockCtx.Err() == nil {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not open lock file: %w", err)
+		}
+
+		locked, err := l.TryLockContext(lockCtx, 500*time.Millisecond)
+		if os.IsNotExist(err) && lockCtx.Err() == nil {
+			_ = f.Close()
+			continue
+		}
+		if !locked || err != nil {
+			_ = f.Close()
+			return nil, fmt.Errorf("could not lock: %s", l.Path())
+		}
+
+		linked, err := isLinked(f)
+		_ = f.Close()
+		if err != nil {
+			_ = l.Unlock()
+			return nil, err
+		}
+		if !linked {
+			_ = l.Unlock()
+			if lockCtx.Err() != nil {
+				return nil, fmt.Errorf("could not lock: %s", l.Path())
+			}
+			continue
+		}
+
+		return l, nil
+	}
+}
+
+// isLinked reports whether the given open file is still the file at its path.
+// A lock file opened before being removed by a concurrent delete or compaction is orphaned,
+// so that locking it does not exclude writers locking the new file at the same path.
+// The lock is held by a handle of its own, therefore the file is opened before locking and
+// only a file which has been linked at its path all along can have been locked.
+func isLinked(f *os.File) (bool, error) {
+	openInfo, err := f.Stat()
+	if err != nil {
+		return false, fmt.Errorf("could not access lock file: %w", err)
+	}
+
+	info, err := os.Stat(f.Name())
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("could not access lock file: %w", err)
+	}
+
+	return os.SameFile(openInfo, info), nil
+}
+
+// marshalObject serializes the module without its coordinates, which the path of its pointer already holds,
+// so that identical modules stored under different coordinates share the same object.
+func marshalObject(module *spec.Module) ([]byte, error) {
+	object := proto.Clone(module).(*spec.Module)
+	object.Namespace = ""
+	object.Name = ""
+	object.Type = ""
+	object.Version.Name = ""
+
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(object)
+	if err != nil {
+		return nil, fmt.Errorf("could not marhsal proto: %w", err)
+	}
+	return data, nil
+}
+
+// unmarshalObject deserializes an object and restores the given coordinates of the module.
+func unmarshalObject(data []byte, namespace string, name string, type_ string, version string) (*spec.Module, error) {
+	m := &spec.Module{}
+	if err := proto.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("could not unmarhsal proto: %w", err)
+	}
+
+	m.Namespace = namespace
+	m.Name = name
+	m.Type = type_
+	if m.Version == nil {
+		m.Version = &spec.ModuleVersion{}
+	}
+	m.Version.Name = version
+
+	return m, nil
+}
+
+// writeObject stores the given data under its digest unless already stored and returns the digest.
+func (r *fileRepository) writeObject(data []byte) (string, error) {
+	sum := sha256.Sum256(data)
+	digest := hex.EncodeToString(sum[:])
+	absObjectFilePath := path.Join(r.objectsPath, digest)
+
+	if _, err := os.Stat(absObjectFilePath); err == nil {
+		return digest, nil
+	}
+
+	if err := os.MkdirAll(r.objectsPath, os.ModePerm); err != nil && !os.IsExist(err) {
+		return "", fmt.Errorf("could not create directory: %w", err)
+	}
+
+	// write to a temporary file first, so that an object is never read partially written
+	f, err := ioutil.TempFile(r.objectsPath, digest+".*.tmp")
+	if err != nil {
+		return "", fmt.Errorf("could not create object file: %w", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		return "", fmt.Errorf("could not write object file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(f.Name())
+		return "", fmt.Errorf("could not write object file: %w", err)
+	}
+	if err := os.Rename(f.Name(), absObjectFilePath); err != nil {
+		_ = os.Remove(f.Name())
+		return "", fmt.Errorf("could not write object file: %w", err)
+	}
+
+	return digest, nil
+}
+
+// readObject reads the data stored under the given digest and verifies its integrity.
+func (r *fileRepository) readObject(digest string) ([]byte, error) {
+	data, err := ioutil.ReadFile(path.Join(r.objectsPath, filepath.Base(digest)))
+	if err != nil {
+		return nil, fmt.Errorf("could not read object file: %w", err)
+	}
+
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != digest {
+		return nil, fmt.Errorf("object %s is corrupt: digest mismatch", digest)
+	}
+
+	return data, nil
 }
 
 func (r *fileRepository) newFileLock(absFilePath string) *flock.Flock {
 	return flock.New(absFilePath + ".lock")
 }
 
+// lockObjects locks the objects directory. Writers hold a shared lock while compaction holds an exclusive lock.
+func (r *fileRepository) lockObjects(exclusive bool) (*flock.Flock, error) {
+	l := r.newFileLock(r.objectsPath)
+	lockCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var locked bool
+	var err error
+	if exclusive {
+		locked, err = l.TryLockContext(lockCtx, 500*time.Millisecond)
+	} else {
+		locked, err = l.TryRLockContext(lockCtx, 500*time.Millisecond)
+	}
+	if !locked || err != nil {
+		return nil, fmt.Errorf("could not lock: %s", l.Path())
+	}
+
+	return l, nil
+}
+
 func (r *fileRepository) getAbsoluteModuleNamespaceDirectoryPath(namespace string) string {
 	return path.Join(r.path, namespace)
 }
@@ -124,72 +366,591 @@ func (r *fileRepository) getAbsoluteModuleFilePath(namespace string, name string
 	return path.Join(r.path, namespace, name, type_, fmt.Sprintf("%s.%s", version, moduleFileExtension))
 }
 
-func (r *fileRepository) DeleteNamespace(namespace string) error {
-	if err := os.RemoveAll(r.getAbsoluteModuleNamespaceDirectoryPath(namespace)); err != nil {
-		return err
+func (r *fileRepository) getAbsoluteTombstoneFilePath(absModuleFilePath string) string {
+	return strings.TrimSuffix(absModuleFilePath, moduleFileExtension) + tombstoneFileExtension
+}
+
+func (r *fileRepository) DeleteNamespace(namespace string, opts ...DeleteOption) error {
+	if r.opts.keepTombstones(opts) {
+		return r.markDeleted(r.getAbsoluteModuleNamespaceDirectoryPath(namespace))
 	}
-	return nil
+
+	return r.remove(r.getAbsoluteModuleNamespaceDirectoryPath(namespace))
+}
+
+func (r *fileRepository) DeleteModule(namespace string, name string, opts ...DeleteOption) error {
+	if r.opts.keepTombstones(opts) {
+		return r.markDeleted(r.getAbsoluteModuleNameDirectoryPath(namespace, name))
+	}
+
+	return r.remove(r.getAbsoluteModuleNameDirectoryPath(namespace, name))
+}
+
+func (r *fileRepository) DeleteModuleType(namespace string, name string, type_ string, opts ...DeleteOption) error {
+	if r.opts.keepTombstones(opts) {
+		return r.markDeleted(r.getAbsoluteModuleTypeDirectoryPath(namespace, name, type_))
+	}
+
+	return r.remove(r.getAbsoluteModuleTypeDirectoryPath(namespace, name, type_))
 }
 
-func (r *fileRepository) DeleteModule(namespace string, name string) error {
-	if err := os.RemoveAll(r.getAbsoluteModuleNameDirectoryPath(namespace, name)); err != nil {
+func (r *fileRepository) DeleteModuleVersion(namespace string, name string, type_ string, version string, opts ...DeleteOption) error {
+	filePath := r.getAbsoluteModuleFilePath(namespace, name, type_, version)
+
+	if r.opts.keepTombstones(opts) {
+		if _, err := os.Stat(filePath); err != nil {
+			return nil
+		}
+		return r.markDeleted(filePath)
+	}
+
+	return r.remove(filePath)
+}
+
+// walk calls fn for each module file below the given path.
+func (r *fileRepository) walk(absPath string, fn func(absModuleFilePath string) error) error {
+	if _, err := os.Stat(absPath); os.IsNotExist(err) {
+		return nil
+	}
+
+	return filepath.Walk(absPath, func(p string, info os.FileInfo, err error) error {
+		// entries removed concurrently are skipped
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(p, "."+moduleFileExtension) {
+			return nil
+		}
+		return fn(p)
+	})
+}
+
+// markDeleted creates a tombstone file for each module file below the given path.
+func (r *fileRepository) markDeleted(absPath string) error {
+	var absDirs []string
+	err := r.walk(absPath, func(absModuleFilePath string) error {
+		if err := ioutil.WriteFile(r.getAbsoluteTombstoneFilePath(absModuleFilePath), nil, os.ModePerm); err != nil {
+			return fmt.Errorf("could not write tombstone file: %w", err)
+		}
+		absDirs = append(absDirs, filepath.Dir(absModuleFilePath))
+		return nil
+	})
+	if err != nil {
 		return err
 	}
-	return r.cleanup(r.getAbsoluteModuleNamespaceDirectoryPath(namespace))
+
+	return r.updateDeletedMarkers(absDirs...)
 }
 
-func (r *fileRepository) DeleteModuleType(namespace string, name string, type_ string) error {
-	if err := os.RemoveAll(r.getAbsoluteModuleTypeDirectoryPath(namespace, name, type_)); err != nil {
+// remove removes each module file below the given path together with its tombstone and lock file
+// and then removes the directories left empty up to the modules directory.
+func (r *fileRepository) remove(absPath string) error {
+	absDirs := []string{filepath.Dir(absPath)}
+	err := r.walk(absPath, func(absModuleFilePath string) error {
+		if err := os.Remove(absModuleFilePath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("could not remove module file: %w", err)
+		}
+		if err := os.Remove(r.getAbsoluteTombstoneFilePath(absModuleFilePath)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("could not remove tombstone file: %w", err)
+		}
+		// the lock file would otherwise keep the directory from being cleaned up
+		if _, err := r.removeStaleLockFile(absModuleFilePath + ".lock"); err != nil {
+			return err
+		}
+		absDirs = append(absDirs, filepath.Dir(absModuleFilePath))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if info, err := os.Stat(absPath); err == nil && info.IsDir() {
+		absDirs = append(absDirs, absPath)
+	}
+
+	if err := r.cleanup(absDirs...); err != nil {
 		return err
 	}
-	return r.cleanup(r.getAbsoluteModuleNameDirectoryPath(namespace, name))
+	return r.updateDeletedMarkers(absDirs...)
 }
 
-func (r *fileRepository) DeleteModuleVersion(namespace string, name string, type_ string, version string) error {
-	filePath := r.getAbsoluteModuleFilePath(namespace, name, type_, version)
-	if _, err := os.Stat(filePath); err == nil {
-		if err := os.Remove(filePath); err != nil {
+// isNotEmpty reports whether the error is known to report that a directory is not empty.
+func isNotEmpty(err error) bool {
+	return errors.Is(err, syscall.ENOTEMPTY) || errors.Is(err, syscall.EEXIST)
+}
+
+func (r *fileRepository) isDeleted(absModuleFilePath string) bool {
+	if !r.opts.tombstones {
+		return false
+	}
+	_, err := os.Stat(r.getAbsoluteTombstoneFilePath(absModuleFilePath))
+	return err == nil
+}
+
+// isHidden reports whether the directory only consists of deleted module versions
+// and has to be omitted from listings.
+func (r *fileRepository) isHidden(listOpts listOptions, absDirectoryPath string) (bool, error) {
+	if !r.opts.tombstones || listOpts.includeDeleted {
+		return false, nil
+	}
+
+	_, err := os.Stat(path.Join(absDirectoryPath, deletedMarkerFile))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("could not access marker file: %w", err)
+	}
+
+	return true, nil
+}
+
+// onlyDeleted reports whether the directory only consists of deleted module versions.
+// Only direct entries are inspected, subdirectories are represented by their deleted marker file.
+func (r *fileRepository) onlyDeleted(absDir string) (bool, error) {
+	files, err := ioutil.ReadDir(absDir)
+	if err != nil {
+		return false, err
+	}
+
+	names := make(map[string]bool, len(files))
+	for _, f := range files {
+		names[f.Name()] = true
+	}
+
+	live, deleted := false, false
+	for _, f := range files {
+		switch {
+		case f.IsDir():
+			_, err := os.Stat(path.Join(absDir, f.Name(), deletedMarkerFile))
+			if err == nil {
+				deleted = true
+			} else if os.IsNotExist(err) {
+				live = true
+			} else {
+				return false, err
+			}
+		case strings.HasSuffix(f.Name(), "."+moduleFileExtension):
+			if names[strings.TrimSuffix(f.Name(), moduleFileExtension)+tombstoneFileExtension] {
+				deleted = true
+			} else {
+				live = true
+			}
+		}
+	}
+
+	return deleted && !live, nil
+}
+
+// updateDeletedMarkers updates the deleted marker files of the given directories and their parents,
+// updating children before their parents.
+func (r *fileRepository) updateDeletedMarkers(absDirs ...string) error {
+	if !r.opts.tombstones {
+		return nil
+	}
+
+	for _, dir := range r.withParents(absDirs) {
+		if err := r.updateDeletedMarker(dir); err != nil {
 			return err
 		}
 	}
-	return r.cleanup(r.getAbsoluteModuleTypeDirectoryPath(namespace, name, type_))
+
+	return nil
+}
+
+// withParents returns the given directories and their parents below the modules directory,
+// children before their parents.
+func (r *fileRepository) withParents(absDirs []string) []string {
+	unique := map[string]bool{}
+	for _, absDir := range absDirs {
+		for dir := absDir; strings.HasPrefix(dir, r.path+string(filepath.Separator)); dir = filepath.Dir(dir) {
+			unique[dir] = true
+		}
+	}
+
+	return deepestFirst(unique)
 }
 
-func (r *fileRepository) cleanup(path string) error {
-	splitPath := filepath.SplitList(path)
+// deepestFirst returns the given set of directories ordered by depth, children before their parents.
+func deepestFirst(unique map[string]bool) []string {
+	dirs := make([]string, 0, len(unique))
+	for dir := range unique {
+		dirs = append(dirs, dir)
+	}
+	sort.Slice(dirs, func(i, j int) bool {
+		return strings.Count(dirs[i], string(filepath.Separator)) > strings.Count(dirs[j], string(filepath.Separator))
+	})
+
+	return dirs
+}
 
-	for i := len(splitPath) - 1; i <= 0; i-- {
-		pathSeg := splitPath[i]
+// updateDeletedMarker creates or removes the deleted marker file of the directory.
+// AddModule removes the marker files after writing the module file, therefore the state is checked
+// again after each update, so that a concurrently added module version never remains hidden.
+func (r *fileRepository) updateDeletedMarker(absDir string) error {
+	absMarkerFilePath := path.Join(absDir, deletedMarkerFile)
 
-		if pathSeg == modulesDirectory {
+	hidden, err := r.onlyDeleted(absDir)
+	for {
+		if os.IsNotExist(err) {
 			return nil
 		}
-		subPath := filepath.Join(splitPath[0:i]...)
+		if err != nil {
+			return fmt.Errorf("could not list files: %w", err)
+		}
+
+		if hidden {
+			err = ioutil.WriteFile(absMarkerFilePath, nil, os.ModePerm)
+		} else {
+			err = os.Remove(absMarkerFilePath)
+		}
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("could not update marker file: %w", err)
+		}
+
+		var stillHidden bool
+		stillHidden, err = r.onlyDeleted(absDir)
+		if err == nil && stillHidden == hidden {
+			return nil
+		}
+		hidden = stillHidden
+	}
+}
+
+// removeDeletedMarkers removes the deleted marker files of the given module type directory and its parents,
+// children before their parents.
+func (r *fileRepository) removeDeletedMarkers(namespace string, name string, type_ string) error {
+	if !r.opts.tombstones {
+		return nil
+	}
+
+	for _, dir := range []string{
+		r.getAbsoluteModuleTypeDirectoryPath(namespace, name, type_),
+		r.getAbsoluteModuleNameDirectoryPath(namespace, name),
+		r.getAbsoluteModuleNamespaceDirectoryPath(namespace),
+	} {
+		if err := os.Remove(path.Join(dir, deletedMarkerFile)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("could not remove marker file: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// cleanup removes each given directory and its parents up to the modules directory as long as they are empty.
+// Deeper directories are cleaned up first, so that a parent is only inspected once its children are gone.
+func (r *fileRepository) cleanup(absDirs ...string) error {
+	unique := make(map[string]bool, len(absDirs))
+	for _, absDir := range absDirs {
+		unique[absDir] = true
+	}
+
+	for _, absDir := range deepestFirst(unique) {
+		for dir := absDir; strings.HasPrefix(dir, r.path+string(filepath.Separator)); dir = filepath.Dir(dir) {
+			removed, err := r.removeEmptyDirectory(dir)
+			if err != nil {
+				return err
+			}
+			if !removed {
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+// removeEmptyDirectory removes the directory if it is empty and reports whether it has been removed.
+// A directory only containing its deleted marker file counts as empty.
+func (r *fileRepository) removeEmptyDirectory(absDir string) (bool, error) {
+	files, err := ioutil.ReadDir(absDir)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("could not list files: %w", err)
+	}
+
+	if len(files) == 1 && files[0].Name() == deletedMarkerFile {
+		if err := os.Remove(path.Join(absDir, deletedMarkerFile)); err != nil && !os.IsNotExist(err) {
+			return false, fmt.Errorf("could not remove marker file: %w", err)
+		}
+		files = nil
+	}
+	if len(files) > 0 {
+		return false, nil
+	}
+
+	err = r.removeDirectory(absDir)
+	// a concurrent writer may have created a file meanwhile
+	if os.IsNotExist(err) || isNotEmpty(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("could not remove directory: %w", err)
+	}
+
+	return true, nil
+}
+
+// Ping checks that the repository directory and, if already created, the modules directory are accessible.
+func (r *fileRepository) Ping(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
-		if _, err := os.Stat(subPath); os.IsNotExist(err) {
+	info, err := os.Stat(filepath.Dir(r.path))
+	if err != nil {
+		return fmt.Errorf("could not access repository directory: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("repository path is not a directory: %s", filepath.Dir(r.path))
+	}
+
+	info, err = os.Stat(r.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not access modules directory: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("modules path is not a directory: %s", r.path)
+	}
+
+	return nil
+}
+
+// Compact removes stale lock files and empty directories below the modules directory, which failed writes and
+// concurrent deletes leave behind,
+// as well as objects no longer referenced by any module file.
+// A lock file is stale if its module file does not exist and the lock is not held.
+func (r *fileRepository) Compact() (CompactResult, error) {
+	var result CompactResult
+
+	if _, err := os.Stat(r.path); os.IsNotExist(err) {
+		return result, nil
+	}
+
+	var lockFiles []string
+	var dirs []string
+	err := filepath.Walk(r.path, func(p string, info os.FileInfo, err error) error {
+		// entries removed concurrently are skipped
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if p != r.path {
+				dirs = append(dirs, p)
+			}
 			return nil
 		}
+		if strings.HasSuffix(p, "."+moduleFileExtension+".lock") {
+			lockFiles = append(lockFiles, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return result, fmt.Errorf("could not walk repository: %w", err)
+	}
 
-		files, err := ioutil.ReadDir(subPath)
+	for _, lockFile := range lockFiles {
+		removed, err := r.removeStaleLockFile(lockFile)
 		if err != nil {
-			return fmt.Errorf("could not list files: %w", err)
+			return result, err
+		}
+		if removed {
+			result.LockFiles = append(result.LockFiles, lockFile)
+		}
+	}
+
+	// walk yields parents before children, so removing in reverse order empties nested directories first
+	for i := len(dirs) - 1; i >= 0; i-- {
+		removed, err := r.removeEmptyDirectory(dirs[i])
+		if err != nil {
+			return result, err
+		}
+		if removed {
+			result.Directories = append(result.Directories, dirs[i])
 		}
+	}
 
-		if len(files) == 0 {
-			return os.Remove(subPath)
+	if r.opts.layout == ContentAddressed {
+		result.Objects, err = r.collectObjects()
+		if err != nil {
+			return result, err
 		}
+	}
+
+	return result, nil
+}
+
+// collectObjects removes all objects which are not referenced by any module file, including deleted ones.
+func (r *fileRepository) collectObjects() ([]string, error) {
+	if _, err := os.Stat(r.objectsPath); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	l, err := r.lockObjects(true)
+	if err != nil {
+		return nil, err
+	}
+	defer l.Unlock()
 
+	// count the references of each object
+	references := map[string]int{}
+	err = r.walk(r.path, func(absModuleFilePath string) error {
+		digest, err := ioutil.ReadFile(absModuleFilePath)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("could not read module file: %w", err)
+		}
+		references[string(digest)]++
 		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not walk repository: %w", err)
+	}
+
+	files, err := ioutil.ReadDir(r.objectsPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not list objects: %w", err)
+	}
+
+	var removed []string
+	for _, f := range files {
+		// skip temporary files and everything else not named by a digest
+		if _, err := hex.DecodeString(f.Name()); err != nil || len(f.Name()) != 2*sha256.Size {
+			continue
+		}
+		if references[f.Name()] > 0 {
+			continue
+		}
+
+		absObjectFilePath := path.Join(r.objectsPath, f.Name())
+		if err := os.Remove(absObjectFilePath); err != nil && !os.IsNotExist(err) {
+			return removed, fmt.Errorf("could not remove object file: %w", err)
+		}
+		removed = append(removed, absObjectFilePath)
+	}
+
+	return removed, nil
+}
+
+// removeStaleLockFile removes the given lock file if it is stale and reports whether it has been removed.
+func (r *fileRepository) removeStaleLockFile(absLockFilePath string) (bool, error) {
+	absModuleFilePath := strings.TrimSuffix(absLockFilePath, ".lock")
+	if _, err := os.Stat(absModuleFilePath); err == nil {
+		return false, nil
+	}
+
+	f, err := os.Open(absLockFilePath)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("could not open lock file: %w", err)
+	}
+	defer f.Close()
+
+	l := flock.New(absLockFilePath)
+	locked, err := l.TryLock()
+	if err != nil {
+		return false, fmt.Errorf("could not lock: %s", l.Path())
+	}
+	if !locked {
+		return false, nil
+	}
+	defer l.Unlock()
+
+	// the lock file may have been replaced and the module file written since the checks above
+	if linked, err := isLinked(f); err != nil || !linked {
+		return false, err
+	}
+	if _, err := os.Stat(absModuleFilePath); err == nil {
+		return false, nil
+	}
+
+	if err := os.Remove(absLockFilePath); err != nil && !os.IsNotExist(err) {
+		return false, fmt.Errorf("could not remove lock file: %w", err)
+	}
+
+	return true, nil
+}
+
+// Touch updates the modification time of the module file to now.
+func (r *fileRepository) Touch(namespace string, name string, type_ string, version string) (rerr error) {
+	targetAbsModuleFilePath := r.getAbsoluteModuleFilePath(namespace, name, type_, version)
+
+	if _, err := os.Stat(targetAbsModuleFilePath); os.IsNotExist(err) {
+		return ErrModuleNotFound
+	} else if err != nil {
+		return fmt.Errorf("could not access module file: %w", err)
+	}
+
+	if r.isDeleted(targetAbsModuleFilePath) {
+		return ErrDeleted
+	}
+
+	l := r.newFileLock(targetAbsModuleFilePath)
+	lockCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	locked, err := l.TryLockContext(lockCtx, 500*time.Millisecond)
+	if !locked || err != nil {
+		return fmt.Errorf("could not lock: %s", l.Path())
+	}
+
+	defer func() {
+		if err := l.Unlock(); err != nil {
+			if rerr != nil {
+				rerr = fmt.Errorf("%s ; could not unlock: %w", rerr.Error(), err)
+			}
+			rerr = fmt.Errorf("could not unlock: %w", err)
+		}
+	}()
+
+	now := time.Now()
+	if err := os.Chtimes(targetAbsModuleFilePath, now, now); err != nil {
+		return fmt.Errorf("could not touch module file: %w", err)
 	}
 
 	return nil
 }
 
+func (r *fileRepository) HasModule(namespace string, name string, type_ string, version string) (bool, error) {
+	targetAbsModuleFilePath := r.getAbsoluteModuleFilePath(namespace, name, type_, version)
+
+	if _, err := os.Stat(targetAbsModuleFilePath); os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("could not access module file: %w", err)
+	}
+
+	return !r.isDeleted(targetAbsModuleFilePath), nil
+}
+
 func (r *fileRepository) GetModule(namespace string, name string, type_ string, version string) (module *spec.Module, rerr error) {
 	targetAbsModuleFilePath := r.getAbsoluteModuleFilePath(namespace, name, type_, version)
 
 	if _, err := os.Stat(targetAbsModuleFilePath); os.IsNotExist(err) {
-		return nil, fmt.Errorf("not found")
+		return nil, ErrModuleNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("could not access module file: %w", err)
+	}
+
+	if r.isDeleted(targetAbsModuleFilePath) {
+		return nil, ErrDeleted
 	}
 
 	l := r.newFileLock(targetAbsModuleFilePath)
@@ -210,10 +971,25 @@ func (r *fileRepository) GetModule(namespace string, name string, type_ string,
 		}
 	}()
 
+	// the module version may have been deleted while waiting for the lock
 	serializedModule, err := ioutil.ReadFile(targetAbsModuleFilePath)
+	if os.IsNotExist(err) {
+		return nil, ErrModuleNotFound
+	}
 	if err != nil {
 		return nil, fmt.Errorf("could not read module file: %w", err)
 	}
+	if r.isDeleted(targetAbsModuleFilePath) {
+		return nil, ErrDeleted
+	}
+
+	if r.opts.layout == ContentAddressed {
+		object, err := r.readObject(string(serializedModule))
+		if err != nil {
+			return nil, err
+		}
+		return unmarshalObject(object, namespace, name, type_, version)
+	}
 
 	m := &spec.Module{}
 	if err := proto.Unmarshal(serializedModule, m); err != nil {
@@ -223,49 +999,82 @@ func (r *fileRepository) GetModule(namespace string, name string, type_ string,
 	return m, nil
 }
 
-func (r *fileRepository) ListModuleNamespaces() ([]string, error) {
-	var namespaces []string
+func (r *fileRepository) GetModules(refs []ModuleRef) (map[ModuleRef]*spec.Module, map[ModuleRef]error) {
+	var (
+		mux     sync.Mutex
+		wg      sync.WaitGroup
+		modules = map[ModuleRef]*spec.Module{}
+		errs    = map[ModuleRef]error{}
+		// bound the number of parallel reads
+		sem = make(chan struct{}, maxConcurrentReads)
+	)
 
-	if _, err := os.Stat(r.path); err == nil {
-		files, err := ioutil.ReadDir(r.path)
-		if err != nil {
-			return nil, fmt.Errorf("could not list directories: %w", err)
-		}
+	for _, ref := range refs {
+		wg.Add(1)
+		sem <- struct{}{}
 
-		for _, f := range files {
-			if f.IsDir() {
-				namespaces = append(namespaces, f.Name())
+		go func(ref ModuleRef) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			m, err := r.GetModule(ref.Namespace, ref.Name, ref.Type, ref.Version)
+
+			mux.Lock()
+			if err != nil {
+				errs[ref] = err
+			} else {
+				modules[ref] = m
 			}
-		}
+			mux.Unlock()
+		}(ref)
 	}
 
-	return namespaces, nil
+	wg.Wait()
+
+	return modules, errs
 }
 
-func (r *fileRepository) ListModuleNames(namespace string) ([]string, error) {
-	var names []string
+// GetModuleHistory skips module versions deleted between listing and reading them.
+func (r *fileRepository) GetModuleHistory(namespace string, name string, type_ string) ([]*spec.Module, error) {
+	versions, err := r.ListModuleVersions(namespace, name, type_)
+	if err != nil {
+		return nil, err
+	}
 
-	directoryPath := r.getAbsoluteModuleNamespaceDirectoryPath(namespace)
-	if _, err := os.Stat(directoryPath); err == nil {
-		files, err := ioutil.ReadDir(directoryPath)
-		if err != nil {
-			return nil, fmt.Errorf("could not list directories: %w", err)
+	modules := make([]*spec.Module, 0, len(versions))
+	for _, version := range versions {
+		m, err := r.GetModule(namespace, name, type_, version)
+		if errors.Is(err, ErrModuleNotFound) || errors.Is(err, ErrDeleted) {
+			continue
 		}
-
-		for _, f := range files {
-			if f.IsDir() {
-				names = append(names, f.Name())
-			}
+		if err != nil {
+			return nil, fmt.Errorf("could not get module version %s: %w", version, err)
 		}
+		modules = append(modules, m)
 	}
 
-	return names, nil
+	sortModulesByVersionDesc(modules)
+
+	return modules, nil
+}
+
+func (r *fileRepository) ListModuleNamespaces(opts ...ListOption) ([]string, error) {
+	return r.listDirectories(r.path, newListOptions(opts))
+}
+
+func (r *fileRepository) ListModuleNames(namespace string, opts ...ListOption) ([]string, error) {
+	return r.listDirectories(r.getAbsoluteModuleNamespaceDirectoryPath(namespace), newListOptions(opts))
 }
 
-func (r *fileRepository) ListModuleTypes(namespace string, name string) ([]string, error) {
-	var types []string
+func (r *fileRepository) ListModuleTypes(namespace string, name string, opts ...ListOption) ([]string, error) {
+	return r.listDirectories(r.getAbsoluteModuleNameDirectoryPath(namespace, name), newListOptions(opts))
+}
+
+func (r *fileRepository) listDirectories(directoryPath string, listOpts listOptions) ([]string, error) {
+	var directories []string
 
-	directoryPath := r.getAbsoluteModuleNameDirectoryPath(namespace, name)
 	if _, err := os.Stat(directoryPath); err == nil {
 		files, err := ioutil.ReadDir(directoryPath)
 		if err != nil {
@@ -273,17 +1082,26 @@ func (r *fileRepository) ListModuleTypes(namespace string, name string) ([]strin
 		}
 
 		for _, f := range files {
-			if f.IsDir() {
-				types = append(types, f.Name())
+			if !f.IsDir() {
+				continue
+			}
+
+			hidden, err := r.isHidden(listOpts, path.Join(directoryPath, f.Name()))
+			if err != nil {
+				return nil, err
+			}
+			if !hidden {
+				directories = append(directories, f.Name())
 			}
 		}
 	}
 
-	return types, nil
+	return directories, nil
 }
 
-func (r *fileRepository) ListModuleVersions(namespace string, name string, type_ string) ([]string, error) {
+func (r *fileRepository) ListModuleVersions(namespace string, name string, type_ string, opts ...ListOption) ([]string, error) {
 	var versions []string
+	listOpts := newListOptions(opts)
 
 	directoryPath := r.getAbsoluteModuleTypeDirectoryPath(namespace, name, type_)
 	if _, err := os.Stat(directoryPath); err == nil {
@@ -293,9 +1111,13 @@ func (r *fileRepository) ListModuleVersions(namespace string, name string, type_
 		}
 
 		for _, f := range files {
-			if strings.HasSuffix(f.Name(), "."+moduleFileExtension) {
-				versions = append(versions, strings.TrimSuffix(f.Name(), "."+moduleFileExtension))
+			if !strings.HasSuffix(f.Name(), "."+moduleFileExtension) {
+				continue
+			}
+			if !listOpts.includeDeleted && r.isDeleted(path.Join(directoryPath, f.Name())) {
+				continue
 			}
+			versions = append(versions, strings.TrimSuffix(f.Name(), "."+moduleFileExtension))
 		}
 	}
 
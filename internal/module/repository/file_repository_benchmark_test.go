@@ -0,0 +1,69 @@
+/*
+Copyright © 2021 The OpenDependency Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repository
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync/atomic"
+	"testing"
+
+	spec "github.com/opendependency/go-spec/pkg/spec/v1"
+)
+
+func BenchmarkFileRepositoryConcurrentAddModule(b *testing.B) {
+	for _, bm := range []struct {
+		name string
+		opts []Option
+	}{
+		{name: "without write queue"},
+		{name: "with write queue", opts: []Option{WithWriteQueue()}},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			tempDir, err := ioutil.TempDir(os.TempDir(), "file-repository")
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer os.RemoveAll(tempDir)
+
+			repo, err := NewFileRepository(tempDir, bm.opts...)
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			var n int64
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					// all writers target the same module type directory and a small set of versions
+					err := repo.AddModule(&spec.Module{
+						Namespace: "com.example",
+						Name:      "product",
+						Type:      "go",
+						Version: &spec.ModuleVersion{
+							Name: fmt.Sprintf("v1.0.%d", atomic.AddInt64(&n, 1)%4),
+						},
+					})
+					if err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		})
+	}
+}
@@ -17,9 +17,18 @@ limitations under the License.
 package repository
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"sync"
+	"time"
 
+	"github.com/gofrs/flock"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	spec "github.com/opendependency/go-spec/pkg/spec/v1"
@@ -52,6 +61,74 @@ var _ = Describe("file repository", func() {
 		}
 	})
 
+	Context("new file repository", func() {
+
+		When("directory does not exist and creation is disabled", func() {
+			It("returns an error", func() {
+				r, err := NewFileRepository(filepath.Join(tempDir, "unknown"), WithCreateDirectory(false))
+				Expect(r).To(BeNil())
+				Expect(err).To(MatchError("repository directory does not exist"))
+			})
+		})
+
+		When("directory does not exist and creation is enabled", func() {
+			It("creates the directory", func() {
+				_, err := NewFileRepository(filepath.Join(tempDir, "unknown"), WithCreateDirectory(true))
+				Expect(err).To(BeNil())
+				Expect(filepath.Join(tempDir, "unknown", modulesDirectory)).To(BeADirectory())
+			})
+		})
+
+		When("directory exists and creation is disabled", func() {
+			It("returns an empty repository", func() {
+				Expect(os.Mkdir(filepath.Join(tempDir, "empty"), os.ModePerm)).To(BeNil())
+
+				r, err := NewFileRepository(filepath.Join(tempDir, "empty"), WithCreateDirectory(false))
+				Expect(err).To(BeNil())
+
+				namespaces, err := r.ListModuleNamespaces()
+				Expect(err).To(BeNil())
+				Expect(namespaces).To(BeEmpty())
+			})
+		})
+	})
+
+	Context("ping", func() {
+		When("repository directory exists", func() {
+			It("returns nil", func() {
+				Expect(repo.Ping(context.Background())).To(BeNil())
+			})
+		})
+
+		When("repository directory has been removed", func() {
+			It("returns an error", func() {
+				Expect(os.RemoveAll(tempDir)).To(BeNil())
+
+				Expect(repo.Ping(context.Background())).ToNot(BeNil())
+			})
+		})
+
+		When("modules directory has not been created yet", func() {
+			It("returns nil", func() {
+				Expect(os.Mkdir(filepath.Join(tempDir, "empty"), os.ModePerm)).To(BeNil())
+				var err error
+				repo, err = NewFileRepository(filepath.Join(tempDir, "empty"), WithCreateDirectory(false))
+				Expect(err).To(BeNil())
+
+				Expect(repo.Ping(context.Background())).To(BeNil())
+			})
+		})
+
+		When("context is canceled", func() {
+			It("returns the context error", func() {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+
+				Expect(repo.Ping(ctx)).To(Equal(context.Canceled))
+			})
+		})
+	})
+
 	Context("add module", func() {
 
 		var (
@@ -114,6 +191,70 @@ var _ = Describe("file repository", func() {
 		})
 	})
 
+	Context("canonical dependency order", func() {
+
+		newModule := func(dependencies ...*spec.ModuleDependency) *spec.Module {
+			return &spec.Module{
+				Namespace: "com.example",
+				Name:      "product",
+				Type:      "go",
+				Version: &spec.ModuleVersion{
+					Name: "v1.0.0",
+				},
+				Annotations: map[string]string{
+					"team":  "payments",
+					"owner": "alice",
+					"tier":  "backend",
+				},
+				Dependencies: dependencies,
+			}
+		}
+
+		lib := &spec.ModuleDependency{Namespace: "com.example", Name: "lib", Type: "go", Version: "v1.2.3"}
+		api := &spec.ModuleDependency{Namespace: "com.example", Name: "api", Type: "protobuf", Version: "v2.0.0"}
+		timeLib := &spec.ModuleDependency{Namespace: "com.acme", Name: "time", Type: "go", Version: "v0.1.0"}
+
+		digest := func() [32]byte {
+			data, err := ioutil.ReadFile(repo.getAbsoluteModuleFilePath("com.example", "product", "go", "v1.0.0"))
+			Expect(err).To(BeNil())
+			return sha256.Sum256(data)
+		}
+
+		BeforeEach(func() {
+			var err error
+			repo, err = NewFileRepository(tempDir, WithCanonicalDependencyOrder())
+			if err != nil {
+				Fail(err.Error())
+			}
+		})
+
+		It("stores modules differing only in dependency order identically", func() {
+			Expect(repo.AddModule(newModule(lib, api, timeLib))).To(BeNil())
+			first := digest()
+
+			Expect(repo.AddModule(newModule(timeLib, lib, api))).To(BeNil())
+			second := digest()
+
+			Expect(second).To(Equal(first))
+		})
+
+		It("does not modify the given module", func() {
+			module := newModule(lib, api, timeLib)
+			Expect(repo.AddModule(module)).To(BeNil())
+
+			Expect(module.Dependencies).To(HaveLen(3))
+			Expect(module.Dependencies[0]).To(BeIdenticalTo(lib))
+		})
+
+		It("returns dependencies in canonical order", func() {
+			Expect(repo.AddModule(newModule(lib, api, timeLib))).To(BeNil())
+
+			m, err := repo.GetModule("com.example", "product", "go", "v1.0.0")
+			Expect(err).To(BeNil())
+			Expect(proto.Equal(m, newModule(timeLib, api, lib))).To(BeTrue())
+		})
+	})
+
 	Context("delete namespace", func() {
 
 		BeforeEach(func() {
@@ -222,6 +363,17 @@ var _ = Describe("file repository", func() {
 				err := repo.DeleteModuleType("com.example", "product", "go")
 				Expect(err).To(BeNil())
 			})
+
+			It("removes the empty parent directories", func() {
+				Expect(repo.DeleteModuleType("com.example", "product", "go")).To(BeNil())
+
+				namespaces, err := repo.ListModuleNamespaces()
+				Expect(err).To(BeNil())
+				Expect(namespaces).To(BeEmpty())
+
+				Expect(filepath.Join(tempDir, modulesDirectory, "com.example")).ToNot(BeADirectory())
+				Expect(filepath.Join(tempDir, modulesDirectory)).To(BeADirectory())
+			})
 		})
 	})
 
@@ -260,6 +412,70 @@ var _ = Describe("file repository", func() {
 				Expect(err).To(BeNil())
 			})
 		})
+
+		When("given module version is the only version", func() {
+			It("removes the empty parent directories", func() {
+				Expect(repo.DeleteModuleVersion("com.example", "product", "go", "v1.0.0")).To(BeNil())
+
+				namespaces, err := repo.ListModuleNamespaces()
+				Expect(err).To(BeNil())
+				Expect(namespaces).To(BeEmpty())
+
+				Expect(filepath.Join(tempDir, modulesDirectory, "com.example")).ToNot(BeADirectory())
+				Expect(filepath.Join(tempDir, modulesDirectory)).To(BeADirectory())
+			})
+
+			It("keeps the lock file and directories while the module version is locked", func() {
+				l := flock.New(filepath.Join(tempDir, modulesDirectory, "com.example", "product", "go", "v1.0.0."+moduleFileExtension+".lock"))
+				locked, err := l.TryLock()
+				Expect(err).To(BeNil())
+				Expect(locked).To(BeTrue())
+				defer l.Unlock()
+
+				Expect(repo.DeleteModuleVersion("com.example", "product", "go", "v1.0.0")).To(BeNil())
+
+				Expect(l.Path()).To(BeARegularFile())
+			})
+
+			It("reveals the parent directories when added again", func() {
+				Expect(repo.DeleteModuleVersion("com.example", "product", "go", "v1.0.0")).To(BeNil())
+				Expect(repo.AddModule(&spec.Module{
+					Namespace: "com.example",
+					Name:      "product",
+					Type:      "go",
+					Version: &spec.ModuleVersion{
+						Name: "v1.0.0",
+					},
+				})).To(BeNil())
+
+				namespaces, err := repo.ListModuleNamespaces()
+				Expect(err).To(BeNil())
+				Expect(namespaces).To(Equal([]string{"com.example"}))
+			})
+		})
+
+		When("given module version has siblings", func() {
+			It("keeps the non-empty parent directories", func() {
+				Expect(repo.AddModule(&spec.Module{
+					Namespace: "com.example",
+					Name:      "customer",
+					Type:      "go",
+					Version: &spec.ModuleVersion{
+						Name: "v1.0.0",
+					},
+				})).To(BeNil())
+
+				Expect(repo.DeleteModuleVersion("com.example", "product", "go", "v1.0.0")).To(BeNil())
+
+				namespaces, err := repo.ListModuleNamespaces()
+				Expect(err).To(BeNil())
+				Expect(namespaces).To(Equal([]string{"com.example"}))
+
+				names, err := repo.ListModuleNames("com.example")
+				Expect(err).To(BeNil())
+				Expect(names).To(Equal([]string{"customer"}))
+			})
+		})
 	})
 
 	Context("get module", func() {
@@ -302,6 +518,7 @@ var _ = Describe("file repository", func() {
 					m, err := repo.GetModule(tt.args.namespace, tt.args.name, tt.args.type_, tt.args.version)
 					Expect(m).To(BeNil())
 					Expect(err).To(MatchError("not found"))
+					Expect(errors.Is(err, ErrModuleNotFound)).To(BeTrue())
 				})
 			})
 		}
@@ -313,6 +530,906 @@ var _ = Describe("file repository", func() {
 				Expect(proto.Equal(m, module)).To(BeTrue())
 			})
 		})
+		When("module file is corrupt", func() {
+			It("returns an error other than not found", func() {
+				Expect(ioutil.WriteFile(filepath.Join(tempDir, modulesDirectory, "com.example", "product", "go", "v1.0.0."+moduleFileExtension), []byte("corrupt"), os.ModePerm)).To(BeNil())
+
+				m, err := repo.GetModule("com.example", "product", "go", "v1.0.0")
+				Expect(m).To(BeNil())
+				Expect(err).ToNot(BeNil())
+				Expect(errors.Is(err, ErrModuleNotFound)).To(BeFalse())
+			})
+		})
+	})
+
+	Context("has module", func() {
+
+		BeforeEach(func() {
+			Expect(repo.AddModule(&spec.Module{
+				Namespace: "com.example",
+				Name:      "product",
+				Type:      "go",
+				Version: &spec.ModuleVersion{
+					Name: "v1.0.0",
+				},
+			})).To(BeNil())
+		})
+
+		for _, tt := range []struct {
+			name    string
+			ref     ModuleRef
+			present bool
+		}{
+			{name: "module version is present", ref: ModuleRef{Namespace: "com.example", Name: "product", Type: "go", Version: "v1.0.0"}, present: true},
+			{name: "namespace is absent", ref: ModuleRef{Namespace: "unknown", Name: "product", Type: "go", Version: "v1.0.0"}},
+			{name: "only namespace is present", ref: ModuleRef{Namespace: "com.example", Name: "unknown", Type: "go", Version: "v1.0.0"}},
+			{name: "only namespace and name are present", ref: ModuleRef{Namespace: "com.example", Name: "product", Type: "unknown", Version: "v1.0.0"}},
+			{name: "only version is absent", ref: ModuleRef{Namespace: "com.example", Name: "product", Type: "go", Version: "unknown"}},
+		} {
+			tt := tt
+			When(tt.name, func() {
+				It("reports the presence", func() {
+					ok, err := repo.HasModule(tt.ref.Namespace, tt.ref.Name, tt.ref.Type, tt.ref.Version)
+					Expect(err).To(BeNil())
+					Expect(ok).To(Equal(tt.present))
+				})
+			})
+		}
+
+		When("module version is marked as deleted", func() {
+			It("reports the absence", func() {
+				var err error
+				repo, err = NewFileRepository(tempDir, WithTombstones())
+				Expect(err).To(BeNil())
+				Expect(repo.AddModule(&spec.Module{
+					Namespace: "com.example",
+					Name:      "product",
+					Type:      "go",
+					Version: &spec.ModuleVersion{
+						Name: "v1.0.0",
+					},
+				})).To(BeNil())
+				Expect(repo.DeleteModuleVersion("com.example", "product", "go", "v1.0.0")).To(BeNil())
+
+				ok, err := repo.HasModule("com.example", "product", "go", "v1.0.0")
+				Expect(err).To(BeNil())
+				Expect(ok).To(BeFalse())
+			})
+		})
+	})
+
+	Context("get modules", func() {
+
+		var (
+			module *spec.Module
+		)
+
+		BeforeEach(func() {
+			module = &spec.Module{
+				Namespace: "com.example",
+				Name:      "product",
+				Type:      "go",
+				Version: &spec.ModuleVersion{
+					Name: "v1.0.0",
+				},
+			}
+
+			Expect(repo.AddModule(module)).To(BeNil())
+		})
+
+		When("no references given", func() {
+			It("returns no modules and no errors", func() {
+				modules, errs := repo.GetModules(nil)
+				Expect(modules).To(BeEmpty())
+				Expect(errs).To(BeEmpty())
+			})
+		})
+
+		When("present and absent references given", func() {
+			It("returns present modules and per reference errors", func() {
+				present := ModuleRef{Namespace: "com.example", Name: "product", Type: "go", Version: "v1.0.0"}
+				absent := ModuleRef{Namespace: "com.example", Name: "product", Type: "go", Version: "v2.0.0"}
+
+				modules, errs := repo.GetModules([]ModuleRef{present, absent})
+				Expect(modules).To(HaveLen(1))
+				Expect(proto.Equal(modules[present], module)).To(BeTrue())
+				Expect(errs).To(HaveLen(1))
+				Expect(errs[absent]).To(MatchError("not found"))
+			})
+		})
+	})
+
+	Context("get module history", func() {
+
+		When("no modules added", func() {
+			It("returns empty module slice and no error", func() {
+				modules, err := repo.GetModuleHistory("com.example", "product", "go")
+				Expect(err).To(BeNil())
+				Expect(modules).To(BeEmpty())
+			})
+		})
+
+		When("modules added", func() {
+			BeforeEach(func() {
+				for _, version := range []string{"v1.2.0", "v1.10.0", "v1.2.0-rc.1", "v1.9.3"} {
+					Expect(repo.AddModule(&spec.Module{
+						Namespace: "com.example",
+						Name:      "product",
+						Type:      "go",
+						Version: &spec.ModuleVersion{
+							Name: version,
+						},
+					})).To(BeNil())
+				}
+			})
+
+			It("returns modules sorted by semantic version, newest first", func() {
+				modules, err := repo.GetModuleHistory("com.example", "product", "go")
+				Expect(err).To(BeNil())
+
+				var versions []string
+				for _, m := range modules {
+					versions = append(versions, m.Version.Name)
+				}
+				Expect(versions).To(Equal([]string{"v1.10.0", "v1.9.3", "v1.2.0", "v1.2.0-rc.1"}))
+			})
+
+			for _, tt := range []struct {
+				name       string
+				tombstones bool
+			}{
+				{name: "removed", tombstones: false},
+				{name: "marked as deleted", tombstones: true},
+			} {
+				tt := tt
+				When(fmt.Sprintf("a module version is %s while reading", tt.name), func() {
+					It("skips the module version", func() {
+						var opts []Option
+						if tt.tombstones {
+							opts = append(opts, WithTombstones())
+						}
+						r, err := NewFileRepository(tempDir, opts...)
+						Expect(err).To(BeNil())
+
+						// hold the lock of a listed module version, so that reading it waits for the delete
+						l := r.newFileLock(r.getAbsoluteModuleFilePath("com.example", "product", "go", "v1.9.3"))
+						Expect(l.Lock()).To(BeNil())
+
+						type history struct {
+							modules []*spec.Module
+							err     error
+						}
+						done := make(chan history, 1)
+						go func() {
+							modules, err := r.GetModuleHistory("com.example", "product", "go")
+							done <- history{modules, err}
+						}()
+
+						time.Sleep(100 * time.Millisecond)
+						Expect(r.DeleteModuleVersion("com.example", "product", "go", "v1.9.3")).To(BeNil())
+						Expect(l.Unlock()).To(BeNil())
+
+						h := <-done
+						Expect(h.err).To(BeNil())
+
+						var versions []string
+						for _, m := range h.modules {
+							versions = append(versions, m.Version.Name)
+						}
+						Expect(versions).To(Equal([]string{"v1.10.0", "v1.2.0", "v1.2.0-rc.1"}))
+					})
+				})
+			}
+		})
+
+	})
+
+	Context("tombstones", func() {
+
+		var (
+			module *spec.Module
+		)
+
+		BeforeEach(func() {
+			var err error
+			repo, err = NewFileRepository(tempDir, WithTombstones())
+			if err != nil {
+				Fail(err.Error())
+			}
+
+			module = &spec.Module{
+				Namespace: "com.example",
+				Name:      "product",
+				Type:      "go",
+				Version: &spec.ModuleVersion{
+					Name: "v1.0.0",
+				},
+			}
+
+			Expect(repo.AddModule(module)).To(BeNil())
+		})
+
+		When("module version is deleted", func() {
+			BeforeEach(func() {
+				Expect(repo.DeleteModuleVersion("com.example", "product", "go", "v1.0.0")).To(BeNil())
+			})
+
+			It("returns deleted error on get", func() {
+				m, err := repo.GetModule("com.example", "product", "go", "v1.0.0")
+				Expect(m).To(BeNil())
+				Expect(err).To(Equal(ErrDeleted))
+			})
+
+			It("excludes the deleted version from listings", func() {
+				versions, err := repo.ListModuleVersions("com.example", "product", "go")
+				Expect(err).To(BeNil())
+				Expect(versions).To(BeEmpty())
+
+				namespaces, err := repo.ListModuleNamespaces()
+				Expect(err).To(BeNil())
+				Expect(namespaces).To(BeEmpty())
+			})
+
+			It("includes the deleted version in listings on request", func() {
+				versions, err := repo.ListModuleVersions("com.example", "product", "go", IncludeDeleted())
+				Expect(err).To(BeNil())
+				Expect(versions).To(ConsistOf("v1.0.0"))
+
+				namespaces, err := repo.ListModuleNamespaces(IncludeDeleted())
+				Expect(err).To(BeNil())
+				Expect(namespaces).To(ConsistOf("com.example"))
+			})
+
+			It("restores the module version when added again", func() {
+				Expect(repo.AddModule(module)).To(BeNil())
+
+				m, err := repo.GetModule("com.example", "product", "go", "v1.0.0")
+				Expect(err).To(BeNil())
+				Expect(proto.Equal(m, module)).To(BeTrue())
+			})
+
+			It("removes the module version when purged", func() {
+				Expect(repo.DeleteModuleVersion("com.example", "product", "go", "v1.0.0", Purge())).To(BeNil())
+
+				m, err := repo.GetModule("com.example", "product", "go", "v1.0.0")
+				Expect(m).To(BeNil())
+				Expect(err).To(MatchError("not found"))
+
+				versions, err := repo.ListModuleVersions("com.example", "product", "go", IncludeDeleted())
+				Expect(err).To(BeNil())
+				Expect(versions).To(BeEmpty())
+			})
+		})
+
+		When("namespace is deleted", func() {
+			BeforeEach(func() {
+				Expect(repo.AddModule(&spec.Module{
+					Namespace: "com.other",
+					Name:      "customer",
+					Type:      "go",
+					Version: &spec.ModuleVersion{
+						Name: "v2.0.0",
+					},
+				})).To(BeNil())
+				Expect(repo.DeleteNamespace("com.example")).To(BeNil())
+			})
+
+			It("marks all module versions within the namespace as deleted", func() {
+				_, err := repo.GetModule("com.example", "product", "go", "v1.0.0")
+				Expect(err).To(Equal(ErrDeleted))
+
+				namespaces, err := repo.ListModuleNamespaces()
+				Expect(err).To(BeNil())
+				Expect(namespaces).To(ConsistOf("com.other"))
+			})
+
+			It("marks the directories as deleted", func() {
+				for _, dir := range []string{
+					filepath.Join(tempDir, modulesDirectory, "com.example"),
+					filepath.Join(tempDir, modulesDirectory, "com.example", "product"),
+					filepath.Join(tempDir, modulesDirectory, "com.example", "product", "go"),
+				} {
+					Expect(filepath.Join(dir, deletedMarkerFile)).To(BeAnExistingFile())
+				}
+				Expect(filepath.Join(tempDir, modulesDirectory, "com.other", deletedMarkerFile)).ToNot(BeAnExistingFile())
+			})
+		})
+
+		When("only some module versions are deleted", func() {
+			BeforeEach(func() {
+				Expect(repo.AddModule(&spec.Module{
+					Namespace: "com.example",
+					Name:      "product",
+					Type:      "go",
+					Version: &spec.ModuleVersion{
+						Name: "v2.0.0",
+					},
+				})).To(BeNil())
+				Expect(repo.DeleteModuleVersion("com.example", "product", "go", "v1.0.0")).To(BeNil())
+			})
+
+			It("lists the directories as long as a live module version remains", func() {
+				types, err := repo.ListModuleTypes("com.example", "product")
+				Expect(err).To(BeNil())
+				Expect(types).To(ConsistOf("go"))
+
+				Expect(repo.DeleteModuleVersion("com.example", "product", "go", "v2.0.0")).To(BeNil())
+
+				types, err = repo.ListModuleTypes("com.example", "product")
+				Expect(err).To(BeNil())
+				Expect(types).To(BeEmpty())
+
+				Expect(repo.AddModule(module)).To(BeNil())
+
+				types, err = repo.ListModuleTypes("com.example", "product")
+				Expect(err).To(BeNil())
+				Expect(types).To(ConsistOf("go"))
+			})
+
+			It("hides the directories when the last live module version is purged", func() {
+				Expect(repo.DeleteModuleVersion("com.example", "product", "go", "v2.0.0", Purge())).To(BeNil())
+
+				namespaces, err := repo.ListModuleNamespaces()
+				Expect(err).To(BeNil())
+				Expect(namespaces).To(BeEmpty())
+
+				namespaces, err = repo.ListModuleNamespaces(IncludeDeleted())
+				Expect(err).To(BeNil())
+				Expect(namespaces).To(ConsistOf("com.example"))
+			})
+		})
+	})
+
+	Context("content-addressed layout", func() {
+
+		var (
+			module *spec.Module
+		)
+
+		listObjects := func() []string {
+			files, err := ioutil.ReadDir(filepath.Join(tempDir, objectsDirectory))
+			if err != nil {
+				Fail(err.Error())
+			}
+			var names []string
+			for _, f := range files {
+				names = append(names, f.Name())
+			}
+			return names
+		}
+
+		BeforeEach(func() {
+			var err error
+			repo, err = NewFileRepository(tempDir, WithLayout(ContentAddressed))
+			if err != nil {
+				Fail(err.Error())
+			}
+
+			module = &spec.Module{
+				Namespace: "com.example",
+				Name:      "product",
+				Type:      "go",
+				Version: &spec.ModuleVersion{
+					Name: "v1.0.0",
+				},
+			}
+
+			Expect(repo.AddModule(module)).To(BeNil())
+		})
+
+		It("stores the module without coordinates as object and a pointer at its coordinates", func() {
+			serialized, err := proto.Marshal(&spec.Module{Version: &spec.ModuleVersion{}})
+			Expect(err).To(BeNil())
+			sum := sha256.Sum256(serialized)
+
+			objects := listObjects()
+			Expect(objects).To(HaveLen(1))
+			Expect(objects[0]).To(Equal(hex.EncodeToString(sum[:])))
+
+			pointer, err := ioutil.ReadFile(filepath.Join(tempDir, modulesDirectory, "com.example", "product", "go", "v1.0.0."+moduleFileExtension))
+			Expect(err).To(BeNil())
+			Expect(string(pointer)).To(Equal(objects[0]))
+		})
+
+		It("returns the module on get", func() {
+			m, err := repo.GetModule("com.example", "product", "go", "v1.0.0")
+			Expect(err).To(BeNil())
+			Expect(proto.Equal(m, module)).To(BeTrue())
+		})
+
+		It("does not return the module after deletion", func() {
+			Expect(repo.DeleteModuleVersion("com.example", "product", "go", "v1.0.0")).To(BeNil())
+
+			m, err := repo.GetModule("com.example", "product", "go", "v1.0.0")
+			Expect(m).To(BeNil())
+			Expect(err).ToNot(BeNil())
+		})
+
+		It("keeps the object after deletion until compaction", func() {
+			objects := listObjects()
+
+			Expect(repo.DeleteModuleVersion("com.example", "product", "go", "v1.0.0")).To(BeNil())
+
+			Expect(filepath.Join(tempDir, modulesDirectory, "com.example", "product", "go", "v1.0.0."+moduleFileExtension)).ToNot(BeAnExistingFile())
+			Expect(listObjects()).To(Equal(objects))
+
+			result, err := repo.Compact()
+			Expect(err).To(BeNil())
+			Expect(result.Objects).To(Equal([]string{filepath.Join(tempDir, objectsDirectory, objects[0])}))
+			Expect(listObjects()).To(BeEmpty())
+		})
+
+		It("stores identical content under different coordinates only once", func() {
+			other := &spec.Module{
+				Namespace: "com.other",
+				Name:      "customer",
+				Type:      "npm",
+				Version: &spec.ModuleVersion{
+					Name: "v1.1.0",
+				},
+			}
+			Expect(repo.AddModule(other)).To(BeNil())
+
+			Expect(listObjects()).To(HaveLen(1))
+
+			m, err := repo.GetModule("com.example", "product", "go", "v1.0.0")
+			Expect(err).To(BeNil())
+			Expect(proto.Equal(m, module)).To(BeTrue())
+
+			m, err = repo.GetModule("com.other", "customer", "npm", "v1.1.0")
+			Expect(err).To(BeNil())
+			Expect(proto.Equal(m, other)).To(BeTrue())
+		})
+
+		It("does not write a second object when adding the same coordinates again", func() {
+			Expect(repo.AddModule(module)).To(BeNil())
+
+			Expect(listObjects()).To(HaveLen(1))
+		})
+
+		It("stores different content as separate objects", func() {
+			Expect(repo.AddModule(&spec.Module{
+				Namespace: "com.example",
+				Name:      "product",
+				Type:      "go",
+				Version: &spec.ModuleVersion{
+					Name: "v1.1.0",
+				},
+				Annotations: map[string]string{
+					"owner": "team",
+				},
+			})).To(BeNil())
+
+			Expect(listObjects()).To(HaveLen(2))
+		})
+
+		It("fails on get when the object is corrupt", func() {
+			objects := listObjects()
+			Expect(ioutil.WriteFile(filepath.Join(tempDir, objectsDirectory, objects[0]), []byte("corrupt"), os.ModePerm)).To(BeNil())
+
+			m, err := repo.GetModule("com.example", "product", "go", "v1.0.0")
+			Expect(m).To(BeNil())
+			Expect(err).ToNot(BeNil())
+		})
+
+		When("compacting", func() {
+			It("removes objects no longer referenced", func() {
+				Expect(repo.AddModule(&spec.Module{
+					Namespace: "com.example",
+					Name:      "product",
+					Type:      "go",
+					Version: &spec.ModuleVersion{
+						Name: "v1.1.0",
+					},
+					Annotations: map[string]string{
+						"owner": "team",
+					},
+				})).To(BeNil())
+				pointer, err := ioutil.ReadFile(filepath.Join(tempDir, modulesDirectory, "com.example", "product", "go", "v1.1.0."+moduleFileExtension))
+				Expect(err).To(BeNil())
+				Expect(repo.DeleteModuleVersion("com.example", "product", "go", "v1.1.0")).To(BeNil())
+
+				result, err := repo.Compact()
+				Expect(err).To(BeNil())
+				Expect(result.Objects).To(Equal([]string{filepath.Join(tempDir, objectsDirectory, string(pointer))}))
+				Expect(listObjects()).To(HaveLen(1))
+
+				m, err := repo.GetModule("com.example", "product", "go", "v1.0.0")
+				Expect(err).To(BeNil())
+				Expect(proto.Equal(m, module)).To(BeTrue())
+			})
+
+			It("keeps objects still referenced by other coordinates", func() {
+				Expect(repo.AddModule(&spec.Module{
+					Namespace: "com.example",
+					Name:      "product",
+					Type:      "go",
+					Version: &spec.ModuleVersion{
+						Name: "v1.1.0",
+					},
+				})).To(BeNil())
+				Expect(repo.DeleteModuleVersion("com.example", "product", "go", "v1.0.0")).To(BeNil())
+
+				result, err := repo.Compact()
+				Expect(err).To(BeNil())
+				Expect(result.Objects).To(BeEmpty())
+				Expect(listObjects()).To(HaveLen(1))
+
+				m, err := repo.GetModule("com.example", "product", "go", "v1.1.0")
+				Expect(err).To(BeNil())
+				Expect(m.Version.Name).To(Equal("v1.1.0"))
+			})
+
+			It("keeps objects of deleted module versions with tombstones", func() {
+				var err error
+				repo, err = NewFileRepository(tempDir, WithLayout(ContentAddressed), WithTombstones())
+				Expect(err).To(BeNil())
+				Expect(repo.DeleteModuleVersion("com.example", "product", "go", "v1.0.0")).To(BeNil())
+
+				result, err := repo.Compact()
+				Expect(err).To(BeNil())
+				Expect(result.Objects).To(BeEmpty())
+				Expect(listObjects()).To(HaveLen(1))
+			})
+
+			It("keeps files not named by a digest", func() {
+				Expect(ioutil.WriteFile(filepath.Join(tempDir, objectsDirectory, "unknown.tmp"), nil, os.ModePerm)).To(BeNil())
+
+				result, err := repo.Compact()
+				Expect(err).To(BeNil())
+				Expect(result.Objects).To(BeEmpty())
+				Expect(listObjects()).To(HaveLen(2))
+			})
+		})
+	})
+
+	Context("compact", func() {
+
+		var (
+			modulesDir string
+		)
+
+		BeforeEach(func() {
+			modulesDir = filepath.Join(tempDir, modulesDirectory)
+
+			Expect(repo.AddModule(&spec.Module{
+				Namespace: "com.example",
+				Name:      "product",
+				Type:      "go",
+				Version: &spec.ModuleVersion{
+					Name: "v1.0.0",
+				},
+			})).To(BeNil())
+
+			Expect(os.MkdirAll(filepath.Join(modulesDir, "com.empty", "product", "go"), os.ModePerm)).To(BeNil())
+			Expect(os.MkdirAll(filepath.Join(modulesDir, "com.example", "customer"), os.ModePerm)).To(BeNil())
+			Expect(ioutil.WriteFile(filepath.Join(modulesDir, "com.example", "product", "go", "v0.9.0."+moduleFileExtension+".lock"), nil, os.ModePerm)).To(BeNil())
+		})
+
+		It("removes empty directories and stale lock files", func() {
+			result, err := repo.Compact()
+			Expect(err).To(BeNil())
+
+			Expect(result.Directories).To(ConsistOf(
+				filepath.Join(modulesDir, "com.empty", "product", "go"),
+				filepath.Join(modulesDir, "com.empty", "product"),
+				filepath.Join(modulesDir, "com.empty"),
+				filepath.Join(modulesDir, "com.example", "customer"),
+			))
+			Expect(result.LockFiles).To(ConsistOf(
+				filepath.Join(modulesDir, "com.example", "product", "go", "v0.9.0."+moduleFileExtension+".lock"),
+			))
+			Expect(result.Objects).To(BeEmpty())
+
+			Expect(filepath.Join(modulesDir, "com.empty")).ToNot(BeADirectory())
+			Expect(filepath.Join(modulesDir, "com.example", "customer")).ToNot(BeADirectory())
+			Expect(filepath.Join(modulesDir, "com.example", "product", "go", "v0.9.0."+moduleFileExtension+".lock")).ToNot(BeAnExistingFile())
+		})
+
+		It("keeps modules and their lock files", func() {
+			_, err := repo.Compact()
+			Expect(err).To(BeNil())
+
+			Expect(filepath.Join(modulesDir, "com.example", "product", "go", "v1.0.0."+moduleFileExtension)).To(BeARegularFile())
+			Expect(filepath.Join(modulesDir, "com.example", "product", "go", "v1.0.0."+moduleFileExtension+".lock")).To(BeARegularFile())
+
+			m, err := repo.GetModule("com.example", "product", "go", "v1.0.0")
+			Expect(err).To(BeNil())
+			Expect(m).ToNot(BeNil())
+		})
+
+		It("keeps held lock files", func() {
+			l := repo.newFileLock(filepath.Join(modulesDir, "com.example", "product", "go", "v0.9.0."+moduleFileExtension))
+			Expect(l.Lock()).To(BeNil())
+			defer l.Unlock()
+
+			result, err := repo.Compact()
+			Expect(err).To(BeNil())
+			Expect(result.LockFiles).To(BeEmpty())
+
+			Expect(filepath.Join(modulesDir, "com.example", "product", "go", "v0.9.0."+moduleFileExtension+".lock")).To(BeARegularFile())
+		})
+
+		It("skips directories a concurrent add writes to", func() {
+			module := &spec.Module{
+				Namespace: "com.empty",
+				Name:      "product",
+				Type:      "go",
+				Version: &spec.ModuleVersion{
+					Name: "v1.0.0",
+				},
+			}
+			repo.removeDirectory = func(name string) error {
+				if name == filepath.Join(modulesDir, "com.empty", "product", "go") {
+					Expect(repo.AddModule(module)).To(BeNil())
+				}
+				return os.Remove(name)
+			}
+
+			result, err := repo.Compact()
+			Expect(err).To(BeNil())
+			Expect(result.Directories).To(ConsistOf(
+				filepath.Join(modulesDir, "com.example", "customer"),
+			))
+
+			m, err := repo.GetModule("com.empty", "product", "go", "v1.0.0")
+			Expect(err).To(BeNil())
+			Expect(proto.Equal(m, module)).To(BeTrue())
+		})
+	})
+
+	Context("is linked", func() {
+
+		var (
+			lockFilePath string
+			f            *os.File
+		)
+
+		BeforeEach(func() {
+			lockFilePath = filepath.Join(tempDir, "v1.0.0."+moduleFileExtension+".lock")
+			Expect(ioutil.WriteFile(lockFilePath, nil, os.ModePerm)).To(BeNil())
+
+			var err error
+			f, err = os.Open(lockFilePath)
+			Expect(err).To(BeNil())
+		})
+
+		AfterEach(func() {
+			Expect(f.Close()).To(BeNil())
+		})
+
+		When("file is still at its path", func() {
+			It("returns true", func() {
+				Expect(isLinked(f)).To(BeTrue())
+			})
+		})
+
+		When("file has been removed", func() {
+			It("returns false", func() {
+				Expect(os.Remove(lockFilePath)).To(BeNil())
+
+				Expect(isLinked(f)).To(BeFalse())
+			})
+		})
+
+		When("file has been replaced", func() {
+			It("returns false", func() {
+				Expect(os.Remove(lockFilePath)).To(BeNil())
+				Expect(ioutil.WriteFile(lockFilePath, nil, os.ModePerm)).To(BeNil())
+
+				Expect(isLinked(f)).To(BeFalse())
+			})
+		})
+	})
+
+	Context("concurrent add and delete", func() {
+
+		It("keeps adding and deleting the same module version consistent", func() {
+			module := &spec.Module{
+				Namespace: "com.example",
+				Name:      "product",
+				Type:      "go",
+				Version: &spec.ModuleVersion{
+					Name: "v1.0.0",
+				},
+			}
+
+			var wg sync.WaitGroup
+			errs := make(chan error, 1024)
+			done := make(chan struct{})
+
+			for i := 0; i < 4; i++ {
+				wg.Add(2)
+				go func() {
+					defer wg.Done()
+					for j := 0; j < 25; j++ {
+						errs <- repo.AddModule(module)
+					}
+				}()
+				go func() {
+					defer wg.Done()
+					for j := 0; j < 25; j++ {
+						errs <- repo.DeleteModuleVersion("com.example", "product", "go", "v1.0.0")
+					}
+				}()
+			}
+
+			compacted := make(chan struct{})
+			go func() {
+				defer close(compacted)
+				for {
+					select {
+					case <-done:
+						return
+					default:
+						if _, err := repo.Compact(); err != nil {
+							errs <- err
+							return
+						}
+					}
+				}
+			}()
+
+			wg.Wait()
+			close(done)
+			<-compacted
+			close(errs)
+
+			for err := range errs {
+				Expect(err).To(BeNil())
+			}
+
+			Expect(repo.AddModule(module)).To(BeNil())
+
+			m, err := repo.GetModule("com.example", "product", "go", "v1.0.0")
+			Expect(err).To(BeNil())
+			Expect(proto.Equal(m, module)).To(BeTrue())
+
+			namespaces, err := repo.ListModuleNamespaces()
+			Expect(err).To(BeNil())
+			Expect(namespaces).To(Equal([]string{"com.example"}))
+
+			Expect(repo.DeleteModuleVersion("com.example", "product", "go", "v1.0.0")).To(BeNil())
+
+			namespaces, err = repo.ListModuleNamespaces()
+			Expect(err).To(BeNil())
+			Expect(namespaces).To(BeEmpty())
+		})
+	})
+
+	Context("touch", func() {
+
+		var (
+			moduleFilePath string
+		)
+
+		BeforeEach(func() {
+			Expect(repo.AddModule(&spec.Module{
+				Namespace: "com.example",
+				Name:      "product",
+				Type:      "go",
+				Version: &spec.ModuleVersion{
+					Name: "v1.0.0",
+				},
+			})).To(BeNil())
+
+			moduleFilePath = filepath.Join(tempDir, modulesDirectory, "com.example", "product", "go", "v1.0.0."+moduleFileExtension)
+
+			past := time.Now().Add(-time.Hour)
+			Expect(os.Chtimes(moduleFilePath, past, past)).To(BeNil())
+		})
+
+		It("advances the modification time without changing the content", func() {
+			before, err := os.Stat(moduleFilePath)
+			Expect(err).To(BeNil())
+			contentBefore, err := ioutil.ReadFile(moduleFilePath)
+			Expect(err).To(BeNil())
+
+			Expect(repo.Touch("com.example", "product", "go", "v1.0.0")).To(BeNil())
+
+			after, err := os.Stat(moduleFilePath)
+			Expect(err).To(BeNil())
+			Expect(after.ModTime()).To(BeTemporally(">", before.ModTime()))
+
+			contentAfter, err := ioutil.ReadFile(moduleFilePath)
+			Expect(err).To(BeNil())
+			Expect(sha256.Sum256(contentAfter)).To(Equal(sha256.Sum256(contentBefore)))
+		})
+
+		It("returns an error if the module does not exist", func() {
+			Expect(repo.Touch("com.example", "product", "go", "v2.0.0")).To(MatchError("not found"))
+		})
+	})
+
+	Context("write queue", func() {
+
+		BeforeEach(func() {
+			var err error
+			repo, err = NewFileRepository(tempDir, WithWriteQueue())
+			if err != nil {
+				Fail(err.Error())
+			}
+		})
+
+		It("stores all concurrently added module versions", func() {
+			var wg sync.WaitGroup
+			errs := make(chan error, 32)
+
+			for i := 0; i < 32; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					errs <- repo.AddModule(&spec.Module{
+						Namespace: "com.example",
+						Name:      "product",
+						Type:      "go",
+						Version: &spec.ModuleVersion{
+							Name: fmt.Sprintf("v1.0.%d", i),
+						},
+					})
+				}(i)
+			}
+
+			wg.Wait()
+			close(errs)
+
+			for err := range errs {
+				Expect(err).To(BeNil())
+			}
+
+			versions, err := repo.ListModuleVersions("com.example", "product", "go")
+			Expect(err).To(BeNil())
+			Expect(versions).To(HaveLen(32))
+
+			for _, version := range versions {
+				m, err := repo.GetModule("com.example", "product", "go", version)
+				Expect(err).To(BeNil())
+				Expect(m.Version.Name).To(Equal(version))
+			}
+
+			repo.writeQueue.mux.Lock()
+			defer repo.writeQueue.mux.Unlock()
+			Expect(repo.writeQueue.queues).To(BeEmpty())
+		})
+
+		It("forgets directories without pending writes", func() {
+			for i := 0; i < 8; i++ {
+				Expect(repo.AddModule(&spec.Module{
+					Namespace: "com.example",
+					Name:      fmt.Sprintf("product-%d", i),
+					Type:      "go",
+					Version: &spec.ModuleVersion{
+						Name: "v1.0.0",
+					},
+				})).To(BeNil())
+			}
+
+			Expect(repo.writeQueue.queues).To(BeEmpty())
+		})
+
+		It("keeps a directory while writers wait for it", func() {
+			release := repo.writeQueue.acquire("dir")
+
+			acquired := make(chan func())
+			go func() {
+				acquired <- repo.writeQueue.acquire("dir")
+			}()
+
+			Eventually(func() int {
+				repo.writeQueue.mux.Lock()
+				defer repo.writeQueue.mux.Unlock()
+				return repo.writeQueue.queues["dir"].refs
+			}).Should(Equal(2))
+			Consistently(acquired).ShouldNot(Receive())
+
+			release()
+			var releaseWaiting func()
+			Eventually(acquired).Should(Receive(&releaseWaiting))
+			Expect(repo.writeQueue.queues).To(HaveKey("dir"))
+
+			releaseWaiting()
+			Expect(repo.writeQueue.queues).To(BeEmpty())
+		})
 	})
 
 	Context("list module namespaces", func() {
@@ -0,0 +1,346 @@
+/*
+Copyright © 2021 The OpenDependency Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	spec "github.com/opendependency/go-spec/pkg/spec/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// jsonContentType is the content type of modules and listings.
+const jsonContentType = "application/json"
+
+// NewHTTPRepository creates a new repository accessing a remote module registry under the given base URL.
+// Modules are transferred in the JSON mapping of their protobuf messages and listings as JSON string arrays.
+// If client is nil, http.DefaultClient is used.
+func NewHTTPRepository(baseURL string, client *http.Client, opts ...HTTPOption) (*httpRepository, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse base url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported base url scheme: %q", u.Scheme)
+	}
+	// request urls are built by appending to the base url
+	if u.RawQuery != "" || u.ForceQuery || u.Fragment != "" {
+		return nil, errors.New("base url must not contain a query or fragment")
+	}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &httpRepository{
+		opts:    newHTTPOptions(opts),
+		baseURL: strings.TrimSuffix(u.String(), "/"),
+		client:  client,
+	}, nil
+}
+
+var _ Repository = (*httpRepository)(nil)
+
+type httpRepository struct {
+	opts    httpOptions
+	baseURL string
+	client  *http.Client
+}
+
+// url builds the url of the resource identified by the given path segments.
+// Segments are alternating collection names and escaped identifiers, e.g. namespaces/com.example.
+func (r *httpRepository) url(segments ...string) string {
+	escaped := make([]string, len(segments))
+	for i, segment := range segments {
+		escaped[i] = url.PathEscape(segment)
+	}
+	return r.baseURL + "/" + strings.Join(escaped, "/")
+}
+
+func (r *httpRepository) namespaceURL(namespace string) string {
+	return r.url("namespaces", namespace)
+}
+
+func (r *httpRepository) moduleURL(namespace string, name string) string {
+	return r.url("namespaces", namespace, "modules", name)
+}
+
+func (r *httpRepository) moduleTypeURL(namespace string, name string, type_ string) string {
+	return r.url("namespaces", namespace, "modules", name, "types", type_)
+}
+
+func (r *httpRepository) moduleVersionURL(namespace string, name string, type_ string, version string) string {
+	return r.url("namespaces", namespace, "modules", name, "types", type_, "versions", version)
+}
+
+// do sends a request and returns the response body of a successful response.
+// Error statuses are mapped to the errors returned by the other repositories,
+// a missing resource to ErrModuleNotFound.
+func (r *httpRepository) do(ctx context.Context, method string, url string, contentType string, body []byte) ([]byte, error) {
+	resp, data, err := r.send(ctx, method, url, contentType, body)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return data, nil
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, ErrModuleNotFound
+	case resp.StatusCode == http.StatusGone:
+		return nil, ErrDeleted
+	case resp.StatusCode == http.StatusForbidden:
+		return nil, ErrForbidden
+	default:
+		return nil, fmt.Errorf("unexpected response status: %s", resp.Status)
+	}
+}
+
+// send sends a request and returns the response together with its read body.
+func (r *httpRepository) send(ctx context.Context, method string, url string, contentType string, body []byte) (*http.Response, []byte, error) {
+	if r.opts.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.opts.timeout)
+		defer cancel()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not create request: %w", err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("Accept", jsonContentType)
+	if r.opts.authorization != "" {
+		req.Header.Set("Authorization", r.opts.authorization)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not read response: %w", err)
+	}
+
+	return resp, data, nil
+}
+
+func (r *httpRepository) delete(url string, opts []DeleteOption) error {
+	if newDeleteOptions(opts).purge {
+		url += "?purge=true"
+	}
+	_, err := r.do(context.Background(), http.MethodDelete, url, "", nil)
+	// deleting a missing resource is a no-op like in the other repositories
+	if errors.Is(err, ErrModuleNotFound) {
+		return nil
+	}
+	return err
+}
+
+func (r *httpRepository) list(url string, opts []ListOption) ([]string, error) {
+	if newListOptions(opts).includeDeleted {
+		url += "?includeDeleted=true"
+	}
+
+	data, err := r.do(context.Background(), http.MethodGet, url, "", nil)
+	// listing a missing resource is empty like in the other repositories
+	if errors.Is(err, ErrModuleNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []string
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("could not unmarshal listing: %w", err)
+	}
+
+	return entries, nil
+}
+
+func (r *httpRepository) AddModule(module *spec.Module) error {
+	if module == nil {
+		return errors.New("module must not be nil")
+	}
+
+	if err := module.Validate(); err != nil {
+		return fmt.Errorf("module validation failed: %w", err)
+	}
+
+	serializedModule, err := protojson.Marshal(module)
+	if err != nil {
+		return fmt.Errorf("could not marhsal module: %w", err)
+	}
+
+	_, err = r.do(context.Background(), http.MethodPut, r.moduleVersionURL(module.Namespace, module.Name, module.Type, module.Version.Name), jsonContentType, serializedModule)
+	return err
+}
+
+func (r *httpRepository) DeleteNamespace(namespace string, opts ...DeleteOption) error {
+	return r.delete(r.namespaceURL(namespace), opts)
+}
+
+func (r *httpRepository) DeleteModule(namespace string, name string, opts ...DeleteOption) error {
+	return r.delete(r.moduleURL(namespace, name), opts)
+}
+
+func (r *httpRepository) DeleteModuleType(namespace string, name string, type_ string, opts ...DeleteOption) error {
+	return r.delete(r.moduleTypeURL(namespace, name, type_), opts)
+}
+
+func (r *httpRepository) DeleteModuleVersion(namespace string, name string, type_ string, version string, opts ...DeleteOption) error {
+	return r.delete(r.moduleVersionURL(namespace, name, type_, version), opts)
+}
+
+func (r *httpRepository) GetModule(namespace string, name string, type_ string, version string) (*spec.Module, error) {
+	data, err := r.do(context.Background(), http.MethodGet, r.moduleVersionURL(namespace, name, type_, version), "", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	module := &spec.Module{}
+	if err := protojson.Unmarshal(data, module); err != nil {
+		return nil, fmt.Errorf("could not unmarhsal module: %w", err)
+	}
+
+	return module, nil
+}
+
+// HasModule requests the headers of a module version only.
+func (r *httpRepository) HasModule(namespace string, name string, type_ string, version string) (bool, error) {
+	_, err := r.do(context.Background(), http.MethodHead, r.moduleVersionURL(namespace, name, type_, version), "", nil)
+	if errors.Is(err, ErrModuleNotFound) || errors.Is(err, ErrDeleted) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *httpRepository) GetModules(refs []ModuleRef) (map[ModuleRef]*spec.Module, map[ModuleRef]error) {
+	var (
+		mux     sync.Mutex
+		wg      sync.WaitGroup
+		modules = map[ModuleRef]*spec.Module{}
+		errs    = map[ModuleRef]error{}
+		// bound the number of parallel requests
+		sem = make(chan struct{}, maxConcurrentReads)
+	)
+
+	for _, ref := range refs {
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(ref ModuleRef) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			m, err := r.GetModule(ref.Namespace, ref.Name, ref.Type, ref.Version)
+
+			mux.Lock()
+			if err != nil {
+				errs[ref] = err
+			} else {
+				modules[ref] = m
+			}
+			mux.Unlock()
+		}(ref)
+	}
+
+	wg.Wait()
+
+	return modules, errs
+}
+
+func (r *httpRepository) GetModuleHistory(namespace string, name string, type_ string) ([]*spec.Module, error) {
+	versions, err := r.ListModuleVersions(namespace, name, type_)
+	if err != nil {
+		return nil, err
+	}
+
+	modules := make([]*spec.Module, 0, len(versions))
+	for _, version := range versions {
+		m, err := r.GetModule(namespace, name, type_, version)
+		if err != nil {
+			return nil, fmt.Errorf("could not get module version %s: %w", version, err)
+		}
+		modules = append(modules, m)
+	}
+
+	sortModulesByVersionDesc(modules)
+
+	return modules, nil
+}
+
+func (r *httpRepository) ListModuleNamespaces(opts ...ListOption) ([]string, error) {
+	return r.list(r.url("namespaces"), opts)
+}
+
+func (r *httpRepository) ListModuleNames(namespace string, opts ...ListOption) ([]string, error) {
+	return r.list(r.namespaceURL(namespace)+"/modules", opts)
+}
+
+func (r *httpRepository) ListModuleTypes(namespace string, name string, opts ...ListOption) ([]string, error) {
+	return r.list(r.moduleURL(namespace, name)+"/types", opts)
+}
+
+func (r *httpRepository) ListModuleVersions(namespace string, name string, type_ string, opts ...ListOption) ([]string, error) {
+	return r.list(r.moduleTypeURL(namespace, name, type_)+"/versions", opts)
+}
+
+// Ping requests the health endpoint of the registry.
+// Its status is checked directly, as it does not refer to a module.
+func (r *httpRepository) Ping(ctx context.Context) error {
+	healthURL := r.url("healthz")
+	resp, _, err := r.send(ctx, http.MethodGet, healthURL, "", nil)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("health endpoint not found: %s", healthURL)
+	default:
+		return fmt.Errorf("registry is unhealthy: %s", resp.Status)
+	}
+}
@@ -0,0 +1,433 @@
+/*
+Copyright © 2021 The OpenDependency Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	spec "github.com/opendependency/go-spec/pkg/spec/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+var _ = Describe("http repository", func() {
+	var (
+		backend       *inMemoryRepository
+		server        *httptest.Server
+		authorization string
+		requestsMux   sync.Mutex
+		requests      []string
+		repo          *httpRepository
+	)
+
+	// newRegistryHandler serves the registry api from the backend repository.
+	// Requests for the namespace com.broken fail with an internal server error,
+	// requests for missing resources with not found.
+	newRegistryHandler := func() http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			requestsMux.Lock()
+			authorization = req.Header.Get("Authorization")
+			requests = append(requests, req.Method+" "+req.URL.RequestURI())
+			requestsMux.Unlock()
+
+			if req.URL.Path == "/healthz" {
+				return
+			}
+
+			segments := strings.Split(strings.TrimPrefix(req.URL.Path, "/"), "/")
+			if len(segments) > 1 && segments[1] == "com.broken" {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			writeList := func(entries []string, err error) {
+				if err != nil {
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				if len(entries) == 0 {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				if err := json.NewEncoder(w).Encode(entries); err != nil {
+					Fail(err.Error())
+				}
+			}
+
+			switch {
+			case len(segments) == 8 && (req.Method == http.MethodGet || req.Method == http.MethodHead):
+				if req.Header.Get("Accept") != jsonContentType {
+					w.WriteHeader(http.StatusNotAcceptable)
+					return
+				}
+				m, err := backend.GetModule(segments[1], segments[3], segments[5], segments[7])
+				if err != nil {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				data, err := protojson.Marshal(m)
+				if err != nil {
+					Fail(err.Error())
+				}
+				w.Header().Set("Content-Type", jsonContentType)
+				_, _ = w.Write(data)
+			case len(segments) == 8 && req.Method == http.MethodPut:
+				data, err := ioutil.ReadAll(req.Body)
+				if err != nil {
+					Fail(err.Error())
+				}
+				if req.Header.Get("Content-Type") != jsonContentType {
+					w.WriteHeader(http.StatusUnsupportedMediaType)
+					return
+				}
+				m := &spec.Module{}
+				if err := protojson.Unmarshal(data, m); err != nil {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				if err := backend.AddModule(m); err != nil {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				w.WriteHeader(http.StatusCreated)
+			case len(segments) == 8 && req.Method == http.MethodDelete:
+				if ok, _ := backend.HasModule(segments[1], segments[3], segments[5], segments[7]); !ok {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				if err := backend.DeleteModuleVersion(segments[1], segments[3], segments[5], segments[7]); err != nil {
+					w.WriteHeader(http.StatusInternalServerError)
+				}
+			case len(segments) == 1 && segments[0] == "namespaces":
+				writeList(backend.ListModuleNamespaces())
+			case len(segments) == 7 && segments[6] == "versions":
+				writeList(backend.ListModuleVersions(segments[1], segments[3], segments[5]))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		})
+	}
+
+	BeforeEach(func() {
+		authorization = ""
+		requests = nil
+
+		backend = NewInMemoryRepository()
+		server = httptest.NewServer(newRegistryHandler())
+
+		var err error
+		repo, err = NewHTTPRepository(server.URL+"/", server.Client(), WithHTTPAuthorization("Bearer token"), WithHTTPTimeout(5*time.Second))
+		if err != nil {
+			Fail(err.Error())
+		}
+
+		Expect(backend.AddModule(&spec.Module{
+			Namespace: "com.example",
+			Name:      "product",
+			Type:      "go",
+			Version: &spec.ModuleVersion{
+				Name: "v1.0.0",
+			},
+		})).To(BeNil())
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Context("new http repository", func() {
+		When("base url has an unsupported scheme", func() {
+			It("returns an error", func() {
+				r, err := NewHTTPRepository("file:///tmp", nil)
+				Expect(r).To(BeNil())
+				Expect(err).To(MatchError(`unsupported base url scheme: "file"`))
+			})
+		})
+
+		for _, baseURL := range []string{
+			"https://registry.example.com/api?token=x",
+			"https://registry.example.com/api?",
+			"https://registry.example.com/api#section",
+		} {
+			baseURL := baseURL
+			When(fmt.Sprintf("base url is %s", baseURL), func() {
+				It("returns an error", func() {
+					r, err := NewHTTPRepository(baseURL, nil)
+					Expect(r).To(BeNil())
+					Expect(err).To(MatchError("base url must not contain a query or fragment"))
+				})
+			})
+		}
+	})
+
+	Context("get module", func() {
+		When("module exists", func() {
+			It("returns the module", func() {
+				m, err := repo.GetModule("com.example", "product", "go", "v1.0.0")
+				Expect(err).To(BeNil())
+				Expect(m.Name).To(Equal("product"))
+				Expect(m.Version.Name).To(Equal("v1.0.0"))
+
+				Expect(requests).To(Equal([]string{"GET /namespaces/com.example/modules/product/types/go/versions/v1.0.0"}))
+				Expect(authorization).To(Equal("Bearer token"))
+			})
+		})
+
+		When("module does not exist", func() {
+			It("returns not found", func() {
+				m, err := repo.GetModule("com.example", "product", "go", "v2.0.0")
+				Expect(m).To(BeNil())
+				Expect(err).To(MatchError("not found"))
+				Expect(errors.Is(err, ErrModuleNotFound)).To(BeTrue())
+			})
+		})
+
+		When("registry fails", func() {
+			It("returns an error", func() {
+				m, err := repo.GetModule("com.broken", "product", "go", "v1.0.0")
+				Expect(m).To(BeNil())
+				Expect(err).To(MatchError("unexpected response status: 500 Internal Server Error"))
+			})
+		})
+	})
+
+	Context("has module", func() {
+		It("reports a present module version", func() {
+			ok, err := repo.HasModule("com.example", "product", "go", "v1.0.0")
+			Expect(err).To(BeNil())
+			Expect(ok).To(BeTrue())
+			Expect(requests).To(Equal([]string{"HEAD /namespaces/com.example/modules/product/types/go/versions/v1.0.0"}))
+		})
+
+		It("reports an absent module version", func() {
+			ok, err := repo.HasModule("com.example", "product", "go", "v2.0.0")
+			Expect(err).To(BeNil())
+			Expect(ok).To(BeFalse())
+		})
+
+		It("returns an error if the registry fails", func() {
+			ok, err := repo.HasModule("com.broken", "product", "go", "v1.0.0")
+			Expect(err).ToNot(BeNil())
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	Context("add module", func() {
+		It("stores the module in the registry", func() {
+			Expect(repo.AddModule(&spec.Module{
+				Namespace: "com.example",
+				Name:      "product",
+				Type:      "go",
+				Version: &spec.ModuleVersion{
+					Name: "v1.1.0",
+				},
+			})).To(BeNil())
+
+			versions, err := backend.ListModuleVersions("com.example", "product", "go")
+			Expect(err).To(BeNil())
+			Expect(versions).To(ConsistOf("v1.0.0", "v1.1.0"))
+		})
+
+		It("validates the module before sending it", func() {
+			Expect(repo.AddModule(&spec.Module{})).ToNot(BeNil())
+			Expect(requests).To(BeEmpty())
+		})
+	})
+
+	Context("delete module version", func() {
+		It("deletes the module in the registry", func() {
+			Expect(repo.DeleteModuleVersion("com.example", "product", "go", "v1.0.0", Purge())).To(BeNil())
+
+			Expect(requests).To(Equal([]string{"DELETE /namespaces/com.example/modules/product/types/go/versions/v1.0.0?purge=true"}))
+			_, err := backend.GetModule("com.example", "product", "go", "v1.0.0")
+			Expect(err).ToNot(BeNil())
+		})
+
+		When("module version does not exist", func() {
+			It("succeeds like the other repositories", func() {
+				expected := backend.DeleteModuleVersion("com.example", "product", "go", "v2.0.0")
+
+				err := repo.DeleteModuleVersion("com.example", "product", "go", "v2.0.0")
+				Expect(requests).To(Equal([]string{"DELETE /namespaces/com.example/modules/product/types/go/versions/v2.0.0"}))
+				Expect(expected).To(BeNil())
+				Expect(err).To(BeNil())
+			})
+		})
+
+		When("registry fails", func() {
+			It("returns an error", func() {
+				Expect(repo.DeleteModuleVersion("com.broken", "product", "go", "v1.0.0")).To(MatchError("unexpected response status: 500 Internal Server Error"))
+			})
+		})
+	})
+
+	Context("delete module", func() {
+		When("module does not exist", func() {
+			It("succeeds like the other repositories", func() {
+				expected := backend.DeleteModule("com.example", "other")
+
+				err := repo.DeleteModule("com.example", "other")
+				Expect(requests).To(Equal([]string{"DELETE /namespaces/com.example/modules/other"}))
+				Expect(expected).To(BeNil())
+				Expect(err).To(BeNil())
+			})
+		})
+	})
+
+	Context("list module namespaces", func() {
+		It("returns the namespaces", func() {
+			namespaces, err := repo.ListModuleNamespaces()
+			Expect(err).To(BeNil())
+			Expect(namespaces).To(Equal([]string{"com.example"}))
+		})
+	})
+
+	Context("list module versions", func() {
+		It("returns the versions", func() {
+			versions, err := repo.ListModuleVersions("com.example", "product", "go")
+			Expect(err).To(BeNil())
+			Expect(versions).To(Equal([]string{"v1.0.0"}))
+		})
+
+		When("module type does not exist", func() {
+			It("returns an empty list like the other repositories", func() {
+				expected, expectedErr := backend.ListModuleVersions("com.example", "product", "npm")
+
+				versions, err := repo.ListModuleVersions("com.example", "product", "npm")
+				Expect(requests).To(Equal([]string{"GET /namespaces/com.example/modules/product/types/npm/versions"}))
+				Expect(expectedErr).To(BeNil())
+				Expect(expected).To(BeEmpty())
+				Expect(err).To(BeNil())
+				Expect(versions).To(BeEmpty())
+			})
+		})
+
+		When("registry fails", func() {
+			It("returns an error", func() {
+				versions, err := repo.ListModuleVersions("com.broken", "product", "go")
+				Expect(versions).To(BeNil())
+				Expect(err).To(MatchError("unexpected response status: 500 Internal Server Error"))
+			})
+		})
+	})
+
+	Context("list module names", func() {
+		When("namespace does not exist", func() {
+			It("returns an empty list like the other repositories", func() {
+				expected, expectedErr := backend.ListModuleNames("com.other")
+
+				names, err := repo.ListModuleNames("com.other")
+				Expect(expectedErr).To(BeNil())
+				Expect(expected).To(BeEmpty())
+				Expect(err).To(BeNil())
+				Expect(names).To(BeEmpty())
+			})
+		})
+	})
+
+	Context("get module history", func() {
+		It("returns all versions newest first", func() {
+			Expect(backend.AddModule(&spec.Module{
+				Namespace: "com.example",
+				Name:      "product",
+				Type:      "go",
+				Version: &spec.ModuleVersion{
+					Name: "v1.10.0",
+				},
+			})).To(BeNil())
+
+			modules, err := repo.GetModuleHistory("com.example", "product", "go")
+			Expect(err).To(BeNil())
+			Expect(modules).To(HaveLen(2))
+			Expect(modules[0].Version.Name).To(Equal("v1.10.0"))
+			Expect(modules[1].Version.Name).To(Equal("v1.0.0"))
+		})
+	})
+
+	Context("get modules", func() {
+		It("returns errors per reference", func() {
+			found := ModuleRef{Namespace: "com.example", Name: "product", Type: "go", Version: "v1.0.0"}
+			missing := ModuleRef{Namespace: "com.example", Name: "product", Type: "go", Version: "v2.0.0"}
+
+			modules, errs := repo.GetModules([]ModuleRef{found, missing})
+			Expect(modules).To(HaveLen(1))
+			Expect(modules).To(HaveKey(found))
+			Expect(errs).To(HaveLen(1))
+			Expect(errs[missing]).To(MatchError("not found"))
+		})
+	})
+
+	Context("ping", func() {
+		It("requests the health endpoint", func() {
+			Expect(repo.Ping(context.Background())).To(BeNil())
+			Expect(requests).To(Equal([]string{"GET /healthz"}))
+		})
+
+		When("registry is unreachable", func() {
+			It("returns an error", func() {
+				server.Close()
+
+				Expect(repo.Ping(context.Background())).ToNot(BeNil())
+			})
+		})
+
+		When("health endpoint does not exist", func() {
+			It("returns an error naming the endpoint", func() {
+				server.Config.Handler = http.NotFoundHandler()
+
+				err := repo.Ping(context.Background())
+				Expect(err).To(MatchError("health endpoint not found: " + server.URL + "/healthz"))
+				Expect(errors.Is(err, ErrModuleNotFound)).To(BeFalse())
+			})
+		})
+
+		When("registry is unhealthy", func() {
+			It("returns an error", func() {
+				server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+					w.WriteHeader(http.StatusServiceUnavailable)
+				})
+
+				Expect(repo.Ping(context.Background())).To(MatchError("registry is unhealthy: 503 Service Unavailable"))
+			})
+		})
+
+		When("request exceeds the timeout", func() {
+			It("returns an error", func() {
+				server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+					<-req.Context().Done()
+				})
+
+				var err error
+				repo, err = NewHTTPRepository(server.URL, server.Client(), WithHTTPTimeout(10*time.Millisecond))
+				Expect(err).To(BeNil())
+
+				err = repo.Ping(context.Background())
+				Expect(errors.Is(err, context.DeadlineExceeded)).To(BeTrue())
+			})
+		})
+	})
+})
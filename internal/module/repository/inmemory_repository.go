@@ -17,26 +17,48 @@ limitations under the License.
 package repository
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"sort"
 	"sync"
+	"time"
 
 	spec "github.com/opendependency/go-spec/pkg/spec/v1"
+	"google.golang.org/protobuf/encoding/protowire"
 	"google.golang.org/protobuf/proto"
 )
 
 // NewInMemoryRepository creates a new in-memory repository.
-func NewInMemoryRepository() *inMemoryRepository {
+func NewInMemoryRepository(opts ...Option) *inMemoryRepository {
 	return &inMemoryRepository{
-		data: map[string]map[string]map[string]map[string]*spec.Module{},
+		opts:     newOptions(opts),
+		data:     map[string]map[string]map[string]map[string]*spec.Module{},
+		deleted:  map[ModuleRef]bool{},
+		modified: map[ModuleRef]time.Time{},
+		counts:   map[ModuleRef]versionCount{},
 	}
 }
 
 var _ Repository = (*inMemoryRepository)(nil)
+var _ Touchable = (*inMemoryRepository)(nil)
 
 type inMemoryRepository struct {
-	mux  sync.RWMutex
-	data map[string]map[string]map[string]map[string]*spec.Module
+	opts    options
+	mux     sync.RWMutex
+	data    map[string]map[string]map[string]map[string]*spec.Module
+	deleted map[ModuleRef]bool
+	// modified holds the time each module version was last stored or touched.
+	modified map[ModuleRef]time.Time
+	// counts holds the version counts of each namespace, module and module type,
+	// keyed by a reference with the remaining coordinates left empty.
+	counts map[ModuleRef]versionCount
+}
+
+// versionCount counts the live and deleted module versions below a coordinate path.
+type versionCount struct {
+	live    int
+	deleted int
 }
 
 func (r *inMemoryRepository) AddModule(module *spec.Module) error {
@@ -48,7 +70,12 @@ func (r *inMemoryRepository) AddModule(module *spec.Module) error {
 		return fmt.Errorf("module validation failed: %w", err)
 	}
 
-	clone := proto.Clone(module).(*spec.Module)
+	var clone *spec.Module
+	if r.opts.canonicalize {
+		clone = canonicalizeModule(module)
+	} else {
+		clone = proto.Clone(module).(*spec.Module)
+	}
 
 	r.mux.Lock()
 
@@ -70,46 +97,83 @@ func (r *inMemoryRepository) AddModule(module *spec.Module) error {
 		moduleTypes[clone.Type] = moduleVersions
 	}
 
+	ref := ModuleRef{Namespace: clone.Namespace, Name: clone.Name, Type: clone.Type, Version: clone.Version.Name}
+	if _, ok := moduleVersions[clone.Version.Name]; !ok {
+		r.count(ref, 1, 0)
+	} else if r.deleted[ref] {
+		r.count(ref, 1, -1)
+	}
 	moduleVersions[clone.Version.Name] = clone
+	delete(r.deleted, ref)
+	r.modified[ref] = time.Now()
 
 	r.mux.Unlock()
 
 	return nil
 }
 
-func (r *inMemoryRepository) DeleteNamespace(namespace string) error {
+func (r *inMemoryRepository) DeleteNamespace(namespace string, opts ...DeleteOption) error {
 	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if r.opts.keepTombstones(opts) {
+		r.markDeleted(namespace)
+		return nil
+	}
+
+	r.forget(namespace)
 	delete(r.data, namespace)
-	r.mux.Unlock()
 
 	return nil
 }
 
-func (r *inMemoryRepository) DeleteModule(namespace string, name string) error {
+func (r *inMemoryRepository) DeleteModule(namespace string, name string, opts ...DeleteOption) error {
 	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if r.opts.keepTombstones(opts) {
+		r.markDeleted(namespace, name)
+		return nil
+	}
+
+	r.forget(namespace, name)
 	moduleNames := r.data[namespace]
 	if moduleNames != nil {
 		delete(moduleNames, name)
 	}
-	r.mux.Unlock()
 
 	return nil
 }
 
-func (r *inMemoryRepository) DeleteModuleType(namespace string, name string, type_ string) error {
+func (r *inMemoryRepository) DeleteModuleType(namespace string, name string, type_ string, opts ...DeleteOption) error {
 	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if r.opts.keepTombstones(opts) {
+		r.markDeleted(namespace, name, type_)
+		return nil
+	}
+
+	r.forget(namespace, name, type_)
 	if moduleNames := r.data[namespace]; moduleNames != nil {
 		if moduleTypes := moduleNames[name]; moduleTypes != nil {
 			delete(moduleTypes, type_)
 		}
 	}
-	r.mux.Unlock()
 
 	return nil
 }
 
-func (r *inMemoryRepository) DeleteModuleVersion(namespace string, name string, type_ string, version string) error {
+func (r *inMemoryRepository) DeleteModuleVersion(namespace string, name string, type_ string, version string, opts ...DeleteOption) error {
 	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if r.opts.keepTombstones(opts) {
+		r.markDeleted(namespace, name, type_, version)
+		return nil
+	}
+
+	r.forget(namespace, name, type_, version)
 	if moduleNames := r.data[namespace]; moduleNames != nil {
 		if moduleTypes := moduleNames[name]; moduleTypes != nil {
 			if moduleVersions := moduleTypes[type_]; moduleVersions != nil {
@@ -117,15 +181,130 @@ func (r *inMemoryRepository) DeleteModuleVersion(namespace string, name string,
 			}
 		}
 	}
-	r.mux.Unlock()
 
 	return nil
 }
 
+// walk calls fn for each stored module version below the given coordinate path.
+// The caller must hold the lock.
+func (r *inMemoryRepository) walk(path []string, fn func(ref ModuleRef)) {
+	skip := func(level int, segment string) bool {
+		return level < len(path) && path[level] != segment
+	}
+
+	for namespace, moduleNames := range r.data {
+		if skip(0, namespace) {
+			continue
+		}
+		for name, moduleTypes := range moduleNames {
+			if skip(1, name) {
+				continue
+			}
+			for type_, moduleVersions := range moduleTypes {
+				if skip(2, type_) {
+					continue
+				}
+				for version := range moduleVersions {
+					if skip(3, version) {
+						continue
+					}
+					fn(ModuleRef{Namespace: namespace, Name: name, Type: type_, Version: version})
+				}
+			}
+		}
+	}
+}
+
+// markDeleted marks all module versions below the given coordinate path as deleted.
+// The caller must hold the lock.
+func (r *inMemoryRepository) markDeleted(path ...string) {
+	r.walk(path, func(ref ModuleRef) {
+		if !r.deleted[ref] {
+			r.deleted[ref] = true
+			r.count(ref, -1, 1)
+		}
+	})
+}
+
+// forget removes the deleted marks, modification times and counts of all module versions below the given coordinate path.
+// It must be called before the module versions are removed. The caller must hold the lock.
+func (r *inMemoryRepository) forget(path ...string) {
+	r.walk(path, func(ref ModuleRef) {
+		if r.deleted[ref] {
+			r.count(ref, 0, -1)
+		} else {
+			r.count(ref, -1, 0)
+		}
+		delete(r.deleted, ref)
+		delete(r.modified, ref)
+	})
+}
+
+// count adjusts the version counts of the namespace, module and module type of the given module version.
+// The caller must hold the lock.
+func (r *inMemoryRepository) count(ref ModuleRef, live int, deleted int) {
+	for _, prefix := range []ModuleRef{
+		{Namespace: ref.Namespace},
+		{Namespace: ref.Namespace, Name: ref.Name},
+		{Namespace: ref.Namespace, Name: ref.Name, Type: ref.Type},
+	} {
+		c := r.counts[prefix]
+		c.live += live
+		c.deleted += deleted
+		if c.live == 0 && c.deleted == 0 {
+			delete(r.counts, prefix)
+		} else {
+			r.counts[prefix] = c
+		}
+	}
+}
+
+// isHidden reports whether the entry of the given coordinate path only consists of
+// deleted module versions and has to be omitted from listings.
+// The caller must hold the lock.
+func (r *inMemoryRepository) isHidden(listOpts listOptions, path ...string) bool {
+	if !r.opts.tombstones || listOpts.includeDeleted {
+		return false
+	}
+
+	var segments [4]string
+	copy(segments[:], path)
+	ref := ModuleRef{Namespace: segments[0], Name: segments[1], Type: segments[2], Version: segments[3]}
+	if ref.Version != "" {
+		return r.deleted[ref]
+	}
+
+	c := r.counts[ref]
+	return c.deleted > 0 && c.live == 0
+}
+
+func (r *inMemoryRepository) HasModule(namespace string, name string, type_ string, version string) (bool, error) {
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+
+	if r.opts.tombstones && r.deleted[ModuleRef{Namespace: namespace, Name: name, Type: type_, Version: version}] {
+		return false, nil
+	}
+	if moduleNames := r.data[namespace]; moduleNames != nil {
+		if moduleTypes := moduleNames[name]; moduleTypes != nil {
+			if moduleVersions := moduleTypes[type_]; moduleVersions != nil {
+				_, ok := moduleVersions[version]
+				return ok, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
 func (r *inMemoryRepository) GetModule(namespace string, name string, type_ string, version string) (*spec.Module, error) {
 	var module *spec.Module
 
 	r.mux.RLock()
+	if r.opts.tombstones && r.deleted[ModuleRef{Namespace: namespace, Name: name, Type: type_, Version: version}] {
+		r.mux.RUnlock()
+		return nil, ErrDeleted
+	}
 	if moduleNames := r.data[namespace]; moduleNames != nil {
 		if moduleTypes := moduleNames[name]; moduleTypes != nil {
 			if moduleVersions := moduleTypes[type_]; moduleVersions != nil {
@@ -141,14 +320,74 @@ func (r *inMemoryRepository) GetModule(namespace string, name string, type_ stri
 		return module, nil
 	}
 
-	return nil, fmt.Errorf("not found")
+	return nil, ErrModuleNotFound
+}
+
+// Touch updates the modification time of the module version to now.
+func (r *inMemoryRepository) Touch(namespace string, name string, type_ string, version string) error {
+	ref := ModuleRef{Namespace: namespace, Name: name, Type: type_, Version: version}
+
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if _, ok := r.modified[ref]; !ok {
+		return ErrModuleNotFound
+	}
+	if r.opts.tombstones && r.deleted[ref] {
+		return ErrDeleted
+	}
+
+	r.modified[ref] = time.Now()
+
+	return nil
+}
+
+func (r *inMemoryRepository) GetModules(refs []ModuleRef) (map[ModuleRef]*spec.Module, map[ModuleRef]error) {
+	modules := map[ModuleRef]*spec.Module{}
+	errs := map[ModuleRef]error{}
+
+	for _, ref := range refs {
+		m, err := r.GetModule(ref.Namespace, ref.Name, ref.Type, ref.Version)
+		if err != nil {
+			errs[ref] = err
+			continue
+		}
+		modules[ref] = m
+	}
+
+	return modules, errs
+}
+
+func (r *inMemoryRepository) GetModuleHistory(namespace string, name string, type_ string) ([]*spec.Module, error) {
+	var modules []*spec.Module
+
+	r.mux.RLock()
+	if moduleNames := r.data[namespace]; moduleNames != nil {
+		if moduleTypes := moduleNames[name]; moduleTypes != nil {
+			for version, m := range moduleTypes[type_] {
+				if r.opts.tombstones && r.deleted[ModuleRef{Namespace: namespace, Name: name, Type: type_, Version: version}] {
+					continue
+				}
+				modules = append(modules, proto.Clone(m).(*spec.Module))
+			}
+		}
+	}
+	r.mux.RUnlock()
+
+	sortModulesByVersionDesc(modules)
+
+	return modules, nil
 }
 
-func (r *inMemoryRepository) ListModuleNamespaces() ([]string, error) {
+func (r *inMemoryRepository) ListModuleNamespaces(opts ...ListOption) ([]string, error) {
 	var namespaces []string
+	listOpts := newListOptions(opts)
 
 	r.mux.RLock()
 	for k := range r.data {
+		if r.isHidden(listOpts, k) {
+			continue
+		}
 		namespaces = append(namespaces, k)
 	}
 	r.mux.RUnlock()
@@ -156,11 +395,15 @@ func (r *inMemoryRepository) ListModuleNamespaces() ([]string, error) {
 	return namespaces, nil
 }
 
-func (r *inMemoryRepository) ListModuleNames(namespace string) ([]string, error) {
+func (r *inMemoryRepository) ListModuleNames(namespace string, opts ...ListOption) ([]string, error) {
 	var names []string
+	listOpts := newListOptions(opts)
 
 	r.mux.RLock()
 	for k := range r.data[namespace] {
+		if r.isHidden(listOpts, namespace, k) {
+			continue
+		}
 		names = append(names, k)
 	}
 	r.mux.RUnlock()
@@ -168,12 +411,16 @@ func (r *inMemoryRepository) ListModuleNames(namespace string) ([]string, error)
 	return names, nil
 }
 
-func (r *inMemoryRepository) ListModuleTypes(namespace string, name string) ([]string, error) {
+func (r *inMemoryRepository) ListModuleTypes(namespace string, name string, opts ...ListOption) ([]string, error) {
 	var types []string
+	listOpts := newListOptions(opts)
 
 	r.mux.RLock()
 	if moduleNames := r.data[namespace]; moduleNames != nil {
 		for k := range moduleNames[name] {
+			if r.isHidden(listOpts, namespace, name, k) {
+				continue
+			}
 			types = append(types, k)
 		}
 	}
@@ -182,13 +429,17 @@ func (r *inMemoryRepository) ListModuleTypes(namespace string, name string) ([]s
 	return types, nil
 }
 
-func (r *inMemoryRepository) ListModuleVersions(namespace string, name string, type_ string) ([]string, error) {
+func (r *inMemoryRepository) ListModuleVersions(namespace string, name string, type_ string, opts ...ListOption) ([]string, error) {
 	var versions []string
+	listOpts := newListOptions(opts)
 
 	r.mux.RLock()
 	if moduleNames := r.data[namespace]; moduleNames != nil {
 		if moduleTypes := moduleNames[name]; moduleTypes != nil {
 			for k := range moduleTypes[type_] {
+				if r.isHidden(listOpts, namespace, name, type_, k) {
+					continue
+				}
 				versions = append(versions, k)
 			}
 		}
@@ -197,3 +448,114 @@ func (r *inMemoryRepository) ListModuleVersions(namespace string, name string, t
 
 	return versions, nil
 }
+
+// Ping always succeeds as the in-memory repository is always reachable.
+func (r *inMemoryRepository) Ping(ctx context.Context) error {
+	return nil
+}
+
+// Snapshot serializes all stored module versions including their deleted marks.
+// The snapshot is a stream of records, each consisting of the length-prefixed
+// serialized module followed by a varint deleted flag.
+func (r *inMemoryRepository) Snapshot() ([]byte, error) {
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+
+	var refs []ModuleRef
+	r.walk(nil, func(ref ModuleRef) {
+		refs = append(refs, ref)
+	})
+
+	// sort for a deterministic snapshot
+	sort.Slice(refs, func(i, j int) bool {
+		a, b := refs[i], refs[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.Version < b.Version
+	})
+
+	var snapshot []byte
+	for _, ref := range refs {
+		serializedModule, err := proto.MarshalOptions{Deterministic: true}.Marshal(r.data[ref.Namespace][ref.Name][ref.Type][ref.Version])
+		if err != nil {
+			return nil, fmt.Errorf("could not marhsal proto: %w", err)
+		}
+
+		snapshot = protowire.AppendBytes(snapshot, serializedModule)
+		snapshot = protowire.AppendVarint(snapshot, protowire.EncodeBool(r.deleted[ref]))
+	}
+
+	return snapshot, nil
+}
+
+// Restore replaces all stored module versions with the ones of the given snapshot.
+// The repository is left unchanged if the snapshot is invalid.
+func (r *inMemoryRepository) Restore(snapshot []byte) error {
+	data := map[string]map[string]map[string]map[string]*spec.Module{}
+	deleted := map[ModuleRef]bool{}
+	modified := map[ModuleRef]time.Time{}
+	now := time.Now()
+
+	for len(snapshot) > 0 {
+		serializedModule, n := protowire.ConsumeBytes(snapshot)
+		if n < 0 {
+			return fmt.Errorf("could not read module: %w", protowire.ParseError(n))
+		}
+		snapshot = snapshot[n:]
+
+		deletedFlag, n := protowire.ConsumeVarint(snapshot)
+		if n < 0 {
+			return fmt.Errorf("could not read deleted flag: %w", protowire.ParseError(n))
+		}
+		snapshot = snapshot[n:]
+
+		m := &spec.Module{}
+		if err := proto.Unmarshal(serializedModule, m); err != nil {
+			return fmt.Errorf("could not unmarhsal proto: %w", err)
+		}
+
+		if err := m.Validate(); err != nil {
+			return fmt.Errorf("module validation failed: %w", err)
+		}
+
+		if data[m.Namespace] == nil {
+			data[m.Namespace] = map[string]map[string]map[string]*spec.Module{}
+		}
+		if data[m.Namespace][m.Name] == nil {
+			data[m.Namespace][m.Name] = map[string]map[string]*spec.Module{}
+		}
+		if data[m.Namespace][m.Name][m.Type] == nil {
+			data[m.Namespace][m.Name][m.Type] = map[string]*spec.Module{}
+		}
+		data[m.Namespace][m.Name][m.Type][m.Version.Name] = m
+
+		ref := ModuleRef{Namespace: m.Namespace, Name: m.Name, Type: m.Type, Version: m.Version.Name}
+		if protowire.DecodeBool(deletedFlag) {
+			deleted[ref] = true
+		}
+		modified[ref] = now
+	}
+
+	r.mux.Lock()
+	r.data = data
+	r.deleted = deleted
+	r.modified = modified
+	r.counts = map[ModuleRef]versionCount{}
+	r.walk(nil, func(ref ModuleRef) {
+		if deleted[ref] {
+			r.count(ref, 0, 1)
+		} else {
+			r.count(ref, 1, 0)
+		}
+	})
+	r.mux.Unlock()
+
+	return nil
+}
@@ -17,6 +17,10 @@ limitations under the License.
 package repository
 
 import (
+	"context"
+	"errors"
+	"time"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	spec "github.com/opendependency/go-spec/pkg/spec/v1"
@@ -32,6 +36,12 @@ var _ = Describe("in-memory repository", func() {
 		repo = NewInMemoryRepository()
 	})
 
+	Context("ping", func() {
+		It("returns nil", func() {
+			Expect(repo.Ping(context.Background())).To(BeNil())
+		})
+	})
+
 	Context("add module", func() {
 
 		var (
@@ -103,6 +113,42 @@ var _ = Describe("in-memory repository", func() {
 		})
 	})
 
+	Context("canonical dependency order", func() {
+
+		newModule := func(dependencies ...*spec.ModuleDependency) *spec.Module {
+			return &spec.Module{
+				Namespace: "com.example",
+				Name:      "product",
+				Type:      "go",
+				Version: &spec.ModuleVersion{
+					Name: "v1.0.0",
+				},
+				Annotations: map[string]string{
+					"team":  "payments",
+					"owner": "alice",
+					"tier":  "backend",
+				},
+				Dependencies: dependencies,
+			}
+		}
+
+		lib := &spec.ModuleDependency{Namespace: "com.example", Name: "lib", Type: "go", Version: "v1.2.3"}
+		api := &spec.ModuleDependency{Namespace: "com.example", Name: "api", Type: "protobuf", Version: "v2.0.0"}
+		timeLib := &spec.ModuleDependency{Namespace: "com.acme", Name: "time", Type: "go", Version: "v0.1.0"}
+
+		BeforeEach(func() {
+			repo = NewInMemoryRepository(WithCanonicalDependencyOrder())
+		})
+
+		It("returns dependencies in canonical order", func() {
+			Expect(repo.AddModule(newModule(lib, api, timeLib))).To(BeNil())
+
+			m, err := repo.GetModule("com.example", "product", "go", "v1.0.0")
+			Expect(err).To(BeNil())
+			Expect(proto.Equal(m, newModule(timeLib, api, lib))).To(BeTrue())
+		})
+	})
+
 	Context("delete namespace", func() {
 
 		BeforeEach(func() {
@@ -370,6 +416,7 @@ var _ = Describe("in-memory repository", func() {
 					m, err := repo.GetModule(tt.args.namespace, tt.args.name, tt.args.type_, tt.args.version)
 					Expect(m).To(BeNil())
 					Expect(err).To(MatchError("not found"))
+					Expect(errors.Is(err, ErrModuleNotFound)).To(BeTrue())
 				})
 			})
 		}
@@ -383,6 +430,331 @@ var _ = Describe("in-memory repository", func() {
 		})
 	})
 
+	Context("has module", func() {
+
+		BeforeEach(func() {
+			Expect(repo.AddModule(&spec.Module{
+				Namespace: "com.example",
+				Name:      "product",
+				Type:      "go",
+				Version: &spec.ModuleVersion{
+					Name: "v1.0.0",
+				},
+			})).To(BeNil())
+		})
+
+		for _, tt := range []struct {
+			name    string
+			ref     ModuleRef
+			present bool
+		}{
+			{name: "module version is present", ref: ModuleRef{Namespace: "com.example", Name: "product", Type: "go", Version: "v1.0.0"}, present: true},
+			{name: "namespace is absent", ref: ModuleRef{Namespace: "unknown", Name: "product", Type: "go", Version: "v1.0.0"}},
+			{name: "only namespace is present", ref: ModuleRef{Namespace: "com.example", Name: "unknown", Type: "go", Version: "v1.0.0"}},
+			{name: "only namespace and name are present", ref: ModuleRef{Namespace: "com.example", Name: "product", Type: "unknown", Version: "v1.0.0"}},
+			{name: "only version is absent", ref: ModuleRef{Namespace: "com.example", Name: "product", Type: "go", Version: "unknown"}},
+		} {
+			tt := tt
+			When(tt.name, func() {
+				It("reports the presence", func() {
+					ok, err := repo.HasModule(tt.ref.Namespace, tt.ref.Name, tt.ref.Type, tt.ref.Version)
+					Expect(err).To(BeNil())
+					Expect(ok).To(Equal(tt.present))
+				})
+			})
+		}
+
+		When("module version is marked as deleted", func() {
+			It("reports the absence", func() {
+				repo = NewInMemoryRepository(WithTombstones())
+				Expect(repo.AddModule(&spec.Module{
+					Namespace: "com.example",
+					Name:      "product",
+					Type:      "go",
+					Version: &spec.ModuleVersion{
+						Name: "v1.0.0",
+					},
+				})).To(BeNil())
+				Expect(repo.DeleteModuleVersion("com.example", "product", "go", "v1.0.0")).To(BeNil())
+
+				ok, err := repo.HasModule("com.example", "product", "go", "v1.0.0")
+				Expect(err).To(BeNil())
+				Expect(ok).To(BeFalse())
+			})
+		})
+	})
+
+	Context("get modules", func() {
+
+		var (
+			module *spec.Module
+		)
+
+		BeforeEach(func() {
+			module = &spec.Module{
+				Namespace: "com.example",
+				Name:      "product",
+				Type:      "go",
+				Version: &spec.ModuleVersion{
+					Name: "v1.0.0",
+				},
+			}
+
+			Expect(repo.AddModule(module)).To(BeNil())
+		})
+
+		When("no references given", func() {
+			It("returns no modules and no errors", func() {
+				modules, errs := repo.GetModules(nil)
+				Expect(modules).To(BeEmpty())
+				Expect(errs).To(BeEmpty())
+			})
+		})
+
+		When("present and absent references given", func() {
+			It("returns present modules and per reference errors", func() {
+				present := ModuleRef{Namespace: "com.example", Name: "product", Type: "go", Version: "v1.0.0"}
+				absent := ModuleRef{Namespace: "com.example", Name: "product", Type: "go", Version: "v2.0.0"}
+
+				modules, errs := repo.GetModules([]ModuleRef{present, absent})
+				Expect(modules).To(HaveLen(1))
+				Expect(proto.Equal(modules[present], module)).To(BeTrue())
+				Expect(errs).To(HaveLen(1))
+				Expect(errs[absent]).To(MatchError("not found"))
+			})
+		})
+	})
+
+	Context("get module history", func() {
+
+		When("no modules added", func() {
+			It("returns empty module slice and no error", func() {
+				modules, err := repo.GetModuleHistory("com.example", "product", "go")
+				Expect(err).To(BeNil())
+				Expect(modules).To(BeEmpty())
+			})
+		})
+
+		When("modules added", func() {
+			BeforeEach(func() {
+				for _, version := range []string{"v1.2.0", "v1.10.0", "v1.2.0-rc.1", "v1.9.3"} {
+					Expect(repo.AddModule(&spec.Module{
+						Namespace: "com.example",
+						Name:      "product",
+						Type:      "go",
+						Version: &spec.ModuleVersion{
+							Name: version,
+						},
+					})).To(BeNil())
+				}
+			})
+
+			It("returns modules sorted by semantic version, newest first", func() {
+				modules, err := repo.GetModuleHistory("com.example", "product", "go")
+				Expect(err).To(BeNil())
+
+				var versions []string
+				for _, m := range modules {
+					versions = append(versions, m.Version.Name)
+				}
+				Expect(versions).To(Equal([]string{"v1.10.0", "v1.9.3", "v1.2.0", "v1.2.0-rc.1"}))
+			})
+		})
+
+	})
+
+	Context("tombstones", func() {
+
+		var (
+			module *spec.Module
+		)
+
+		BeforeEach(func() {
+			repo = NewInMemoryRepository(WithTombstones())
+
+			module = &spec.Module{
+				Namespace: "com.example",
+				Name:      "product",
+				Type:      "go",
+				Version: &spec.ModuleVersion{
+					Name: "v1.0.0",
+				},
+			}
+
+			Expect(repo.AddModule(module)).To(BeNil())
+		})
+
+		When("module version is deleted", func() {
+			BeforeEach(func() {
+				Expect(repo.DeleteModuleVersion("com.example", "product", "go", "v1.0.0")).To(BeNil())
+			})
+
+			It("returns deleted error on get", func() {
+				m, err := repo.GetModule("com.example", "product", "go", "v1.0.0")
+				Expect(m).To(BeNil())
+				Expect(err).To(Equal(ErrDeleted))
+			})
+
+			It("excludes the deleted version from listings", func() {
+				versions, err := repo.ListModuleVersions("com.example", "product", "go")
+				Expect(err).To(BeNil())
+				Expect(versions).To(BeEmpty())
+
+				namespaces, err := repo.ListModuleNamespaces()
+				Expect(err).To(BeNil())
+				Expect(namespaces).To(BeEmpty())
+			})
+
+			It("includes the deleted version in listings on request", func() {
+				versions, err := repo.ListModuleVersions("com.example", "product", "go", IncludeDeleted())
+				Expect(err).To(BeNil())
+				Expect(versions).To(ConsistOf("v1.0.0"))
+
+				namespaces, err := repo.ListModuleNamespaces(IncludeDeleted())
+				Expect(err).To(BeNil())
+				Expect(namespaces).To(ConsistOf("com.example"))
+			})
+
+			It("restores the module version when added again", func() {
+				Expect(repo.AddModule(module)).To(BeNil())
+
+				m, err := repo.GetModule("com.example", "product", "go", "v1.0.0")
+				Expect(err).To(BeNil())
+				Expect(proto.Equal(m, module)).To(BeTrue())
+			})
+
+			It("removes the module version when purged", func() {
+				Expect(repo.DeleteModuleVersion("com.example", "product", "go", "v1.0.0", Purge())).To(BeNil())
+
+				m, err := repo.GetModule("com.example", "product", "go", "v1.0.0")
+				Expect(m).To(BeNil())
+				Expect(err).To(MatchError("not found"))
+
+				versions, err := repo.ListModuleVersions("com.example", "product", "go", IncludeDeleted())
+				Expect(err).To(BeNil())
+				Expect(versions).To(BeEmpty())
+			})
+		})
+
+		When("namespace is deleted", func() {
+			BeforeEach(func() {
+				Expect(repo.AddModule(&spec.Module{
+					Namespace: "com.other",
+					Name:      "customer",
+					Type:      "go",
+					Version: &spec.ModuleVersion{
+						Name: "v2.0.0",
+					},
+				})).To(BeNil())
+				Expect(repo.DeleteNamespace("com.example")).To(BeNil())
+			})
+
+			It("marks all module versions within the namespace as deleted", func() {
+				_, err := repo.GetModule("com.example", "product", "go", "v1.0.0")
+				Expect(err).To(Equal(ErrDeleted))
+
+				namespaces, err := repo.ListModuleNamespaces()
+				Expect(err).To(BeNil())
+				Expect(namespaces).To(ConsistOf("com.other"))
+			})
+		})
+
+		When("only some module versions are deleted", func() {
+			BeforeEach(func() {
+				Expect(repo.AddModule(&spec.Module{
+					Namespace: "com.example",
+					Name:      "product",
+					Type:      "go",
+					Version: &spec.ModuleVersion{
+						Name: "v2.0.0",
+					},
+				})).To(BeNil())
+				Expect(repo.DeleteModuleVersion("com.example", "product", "go", "v1.0.0")).To(BeNil())
+			})
+
+			It("lists the entries as long as a live module version remains", func() {
+				types, err := repo.ListModuleTypes("com.example", "product")
+				Expect(err).To(BeNil())
+				Expect(types).To(ConsistOf("go"))
+
+				Expect(repo.DeleteModuleVersion("com.example", "product", "go", "v2.0.0")).To(BeNil())
+
+				types, err = repo.ListModuleTypes("com.example", "product")
+				Expect(err).To(BeNil())
+				Expect(types).To(BeEmpty())
+
+				Expect(repo.AddModule(module)).To(BeNil())
+
+				types, err = repo.ListModuleTypes("com.example", "product")
+				Expect(err).To(BeNil())
+				Expect(types).To(ConsistOf("go"))
+			})
+
+			It("drops all version counts when purged", func() {
+				Expect(repo.DeleteNamespace("com.example", Purge())).To(BeNil())
+
+				Expect(repo.counts).To(BeEmpty())
+				Expect(repo.deleted).To(BeEmpty())
+			})
+		})
+	})
+
+	Context("touch", func() {
+
+		var (
+			ref  ModuleRef
+			past time.Time
+		)
+
+		BeforeEach(func() {
+			repo = NewInMemoryRepository(WithTombstones())
+
+			Expect(repo.AddModule(&spec.Module{
+				Namespace: "com.example",
+				Name:      "product",
+				Type:      "go",
+				Version: &spec.ModuleVersion{
+					Name: "v1.0.0",
+				},
+			})).To(BeNil())
+
+			ref = ModuleRef{Namespace: "com.example", Name: "product", Type: "go", Version: "v1.0.0"}
+			past = time.Now().Add(-time.Hour)
+			repo.modified[ref] = past
+		})
+
+		It("advances the modification time without changing the content", func() {
+			before, err := repo.GetModule("com.example", "product", "go", "v1.0.0")
+			Expect(err).To(BeNil())
+
+			Expect(repo.Touch("com.example", "product", "go", "v1.0.0")).To(BeNil())
+
+			Expect(repo.modified[ref]).To(BeTemporally(">", past))
+
+			after, err := repo.GetModule("com.example", "product", "go", "v1.0.0")
+			Expect(err).To(BeNil())
+			Expect(proto.Equal(after, before)).To(BeTrue())
+		})
+
+		It("returns an error if the module does not exist", func() {
+			Expect(repo.Touch("com.example", "product", "go", "v2.0.0")).To(MatchError("not found"))
+		})
+
+		It("returns an error if the module is deleted", func() {
+			Expect(repo.DeleteModuleVersion("com.example", "product", "go", "v1.0.0")).To(BeNil())
+
+			Expect(repo.Touch("com.example", "product", "go", "v1.0.0")).To(Equal(ErrDeleted))
+			Expect(repo.modified[ref]).To(Equal(past))
+		})
+
+		It("forgets the modification time of purged module versions", func() {
+			Expect(repo.DeleteModule("com.example", "product", Purge())).To(BeNil())
+
+			Expect(repo.modified).To(BeEmpty())
+			Expect(repo.Touch("com.example", "product", "go", "v1.0.0")).To(MatchError("not found"))
+		})
+	})
+
 	Context("list module namespaces", func() {
 
 		When("no modules added", func() {
@@ -543,4 +915,88 @@ var _ = Describe("in-memory repository", func() {
 
 	})
 
+	Context("snapshot and restore", func() {
+
+		BeforeEach(func() {
+			repo = NewInMemoryRepository(WithTombstones())
+
+			for _, module := range []*spec.Module{
+				{
+					Namespace: "com.example",
+					Name:      "product",
+					Type:      "go",
+					Version: &spec.ModuleVersion{
+						Name: "v1.0.0",
+					},
+					Annotations: map[string]string{"team": "payments"},
+					Dependencies: []*spec.ModuleDependency{
+						{Namespace: "com.example", Name: "lib", Type: "go", Version: "v1.2.3"},
+					},
+				},
+				{
+					Namespace: "com.example",
+					Name:      "product",
+					Type:      "go",
+					Version: &spec.ModuleVersion{
+						Name: "v2.0.0",
+					},
+				},
+				{
+					Namespace: "com.other",
+					Name:      "customer",
+					Type:      "helm",
+					Version: &spec.ModuleVersion{
+						Name: "v3.0.0",
+					},
+				},
+			} {
+				Expect(repo.AddModule(module)).To(BeNil())
+			}
+
+			Expect(repo.DeleteModuleVersion("com.example", "product", "go", "v2.0.0")).To(BeNil())
+		})
+
+		It("restores identical contents", func() {
+			snapshot, err := repo.Snapshot()
+			Expect(err).To(BeNil())
+
+			restored := NewInMemoryRepository(WithTombstones())
+			Expect(restored.Restore(snapshot)).To(BeNil())
+
+			Expect(restored.data).To(HaveLen(len(repo.data)))
+			for _, ref := range []ModuleRef{
+				{Namespace: "com.example", Name: "product", Type: "go", Version: "v1.0.0"},
+				{Namespace: "com.other", Name: "customer", Type: "helm", Version: "v3.0.0"},
+			} {
+				expected, err := repo.GetModule(ref.Namespace, ref.Name, ref.Type, ref.Version)
+				Expect(err).To(BeNil())
+				actual, err := restored.GetModule(ref.Namespace, ref.Name, ref.Type, ref.Version)
+				Expect(err).To(BeNil())
+				Expect(proto.Equal(actual, expected)).To(BeTrue())
+			}
+
+			_, err = restored.GetModule("com.example", "product", "go", "v2.0.0")
+			Expect(err).To(Equal(ErrDeleted))
+		})
+
+		It("creates deterministic snapshots", func() {
+			first, err := repo.Snapshot()
+			Expect(err).To(BeNil())
+			second, err := repo.Snapshot()
+			Expect(err).To(BeNil())
+
+			Expect(second).To(Equal(first))
+		})
+
+		When("snapshot is invalid", func() {
+			It("returns an error and keeps the contents", func() {
+				err := repo.Restore([]byte{0xff})
+				Expect(err).To(HaveOccurred())
+
+				_, err = repo.GetModule("com.example", "product", "go", "v1.0.0")
+				Expect(err).To(BeNil())
+			})
+		})
+	})
+
 })
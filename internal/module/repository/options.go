@@ -0,0 +1,177 @@
+/*
+Copyright © 2021 The OpenDependency Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repository
+
+import (
+	"time"
+)
+
+// Option configures a repository.
+type Option func(o *options)
+
+type options struct {
+	tombstones      bool
+	createDirectory bool
+	canonicalize    bool
+	layout          Layout
+	writeQueue      bool
+}
+
+func newOptions(opts []Option) options {
+	o := options{
+		createDirectory: true,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithTombstones makes deletes mark module versions as deleted instead of removing them.
+// Getting a deleted module version returns ErrDeleted.
+// Tombstones are only considered by repositories created with this option.
+func WithTombstones() Option {
+	return func(o *options) {
+		o.tombstones = true
+	}
+}
+
+// WithCreateDirectory controls whether a file repository creates its directory if it does not exist.
+// Without creation, creating a repository for a non-existing directory fails.
+// The directory is created by default.
+func WithCreateDirectory(create bool) Option {
+	return func(o *options) {
+		o.createDirectory = create
+	}
+}
+
+// WithCanonicalDependencyOrder sorts the dependencies of added modules by namespace, name, type and version,
+// so that modules only differing in dependency order are stored identically.
+func WithCanonicalDependencyOrder() Option {
+	return func(o *options) {
+		o.canonicalize = true
+	}
+}
+
+// Layout defines how a file repository stores modules.
+type Layout int
+
+const (
+	// Hierarchical stores each module under its namespace/name/type/version path.
+	Hierarchical Layout = iota
+	// ContentAddressed stores each module without its coordinates under objects/<sha256 digest> and
+	// places a pointer to the object under its namespace/name/type/version path,
+	// so that identical modules stored under several coordinates share one object.
+	ContentAddressed
+)
+
+// WithLayout sets the storage layout of a file repository.
+// The hierarchical layout is used by default.
+func WithLayout(layout Layout) Option {
+	return func(o *options) {
+		o.layout = layout
+	}
+}
+
+// WithWriteQueue serializes writes of a file repository to the same module type directory in-process,
+// so that concurrent writes from the same process do not repeatedly contend on the file lock.
+// The file lock still guards every write against other processes.
+func WithWriteQueue() Option {
+	return func(o *options) {
+		o.writeQueue = true
+	}
+}
+
+// HTTPOption configures an HTTP repository.
+type HTTPOption func(o *httpOptions)
+
+type httpOptions struct {
+	authorization string
+	timeout       time.Duration
+}
+
+func newHTTPOptions(opts []HTTPOption) httpOptions {
+	o := httpOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithHTTPAuthorization sets the Authorization header sent with every request of an HTTP repository.
+func WithHTTPAuthorization(authorization string) HTTPOption {
+	return func(o *httpOptions) {
+		o.authorization = authorization
+	}
+}
+
+// WithHTTPTimeout limits the duration of every request of an HTTP repository.
+// Requests are not limited by default besides the limits of the HTTP client.
+func WithHTTPTimeout(timeout time.Duration) HTTPOption {
+	return func(o *httpOptions) {
+		o.timeout = timeout
+	}
+}
+
+// ListOption configures a list operation.
+type ListOption func(o *listOptions)
+
+type listOptions struct {
+	includeDeleted bool
+}
+
+func newListOptions(opts []ListOption) listOptions {
+	o := listOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// IncludeDeleted includes entries which only consist of deleted module versions.
+func IncludeDeleted() ListOption {
+	return func(o *listOptions) {
+		o.includeDeleted = true
+	}
+}
+
+// DeleteOption configures a delete operation.
+type DeleteOption func(o *deleteOptions)
+
+type deleteOptions struct {
+	purge bool
+}
+
+func newDeleteOptions(opts []DeleteOption) deleteOptions {
+	o := deleteOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// Purge removes the data even if the repository keeps tombstones.
+func Purge() DeleteOption {
+	return func(o *deleteOptions) {
+		o.purge = true
+	}
+}
+
+// keepTombstones reports whether a delete operation with the given options marks module versions as deleted.
+func (o options) keepTombstones(opts []DeleteOption) bool {
+	return o.tombstones && !newDeleteOptions(opts).purge
+}
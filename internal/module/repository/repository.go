@@ -17,29 +17,82 @@ limitations under the License.
 package repository
 
 import (
+	"context"
+	"errors"
+
 	spec "github.com/opendependency/go-spec/pkg/spec/v1"
 )
 
+// ErrModuleNotFound is returned when getting a module version which does not exist.
+var ErrModuleNotFound = errors.New("not found")
+
+// ErrDeleted is returned when getting a module version which has been marked as deleted.
+var ErrDeleted = errors.New("deleted")
+
+// ErrForbidden is returned when accessing a namespace outside the scope of a repository.
+var ErrForbidden = errors.New("forbidden")
+
 // Repository provides access to modules stored in a backend.
 type Repository interface {
 	// AddModule adds the given module.
 	AddModule(module *spec.Module) error
 	// DeleteNamespace deletes a whole module namespace with all modules.
-	DeleteNamespace(namespace string) error
+	DeleteNamespace(namespace string, opts ...DeleteOption) error
 	// DeleteModule deletes a specific module.
-	DeleteModule(namespace string, name string) error
+	DeleteModule(namespace string, name string, opts ...DeleteOption) error
 	// DeleteModuleType deletes a specific module type.
-	DeleteModuleType(namespace string, name string, type_ string) error
+	DeleteModuleType(namespace string, name string, type_ string, opts ...DeleteOption) error
 	// DeleteModuleVersion deletes a specific module version.
-	DeleteModuleVersion(namespace string, name string, type_ string, version string) error
+	DeleteModuleVersion(namespace string, name string, type_ string, version string, opts ...DeleteOption) error
 	// GetModule gets a specific module.
 	GetModule(namespace string, name string, type_ string, version string) (*spec.Module, error)
+	// HasModule reports whether a specific module version exists without reading it.
+	// Module versions marked as deleted do not exist.
+	HasModule(namespace string, name string, type_ string, version string) (bool, error)
+	// GetModules gets multiple specific modules.
+	// Modules which could not be fetched are omitted and their errors are returned per reference instead.
+	GetModules(refs []ModuleRef) (map[ModuleRef]*spec.Module, map[ModuleRef]error)
+	// GetModuleHistory gets all versions of a specific module type sorted by version, newest first.
+	GetModuleHistory(namespace string, name string, type_ string) ([]*spec.Module, error)
 	// ListModuleNamespaces list all module namespaces.
-	ListModuleNamespaces() ([]string, error)
+	ListModuleNamespaces(opts ...ListOption) ([]string, error)
 	// ListModuleNames list all module names within a namespace.
-	ListModuleNames(namespace string) ([]string, error)
+	ListModuleNames(namespace string, opts ...ListOption) ([]string, error)
 	// ListModuleTypes list all module types of a module.
-	ListModuleTypes(namespace string, name string) ([]string, error)
+	ListModuleTypes(namespace string, name string, opts ...ListOption) ([]string, error)
 	// ListModuleVersions list all module versions of a module.
-	ListModuleVersions(namespace string, name string, type_ string) ([]string, error)
+	ListModuleVersions(namespace string, name string, type_ string, opts ...ListOption) ([]string, error)
+	// Ping checks that the backend is reachable without listing its content.
+	Ping(ctx context.Context) error
+}
+
+// Compactable is implemented by repositories which can reclaim storage left behind by previous operations.
+type Compactable interface {
+	// Compact removes leftovers which are no longer needed, such as empty directories and stale lock files,
+	// and reports what has been removed.
+	Compact() (CompactResult, error)
+}
+
+// CompactResult lists the absolute paths of everything removed by a compaction.
+type CompactResult struct {
+	// Directories are the removed empty directories.
+	Directories []string
+	// LockFiles are the removed stale lock files.
+	LockFiles []string
+	// Objects are the removed objects no longer referenced by any module version.
+	Objects []string
+}
+
+// Touchable is implemented by repositories which track when a module version was last stored.
+type Touchable interface {
+	// Touch updates the stored time of a specific module version without changing its content.
+	Touch(namespace string, name string, type_ string, version string) error
+}
+
+// ModuleRef references a specific module version.
+type ModuleRef struct {
+	Namespace string
+	Name      string
+	Type      string
+	Version   string
 }
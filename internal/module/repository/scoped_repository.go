@@ -0,0 +1,172 @@
+/*
+Copyright © 2021 The OpenDependency Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	spec "github.com/opendependency/go-spec/pkg/spec/v1"
+)
+
+// NewScopedRepository creates a new repository restricting reads and writes
+// of the delegate to the allowed namespaces.
+func NewScopedRepository(delegate Repository, allowedNamespaces []string) *scopedRepository {
+	allowed := make(map[string]struct{}, len(allowedNamespaces))
+	for _, namespace := range allowedNamespaces {
+		allowed[namespace] = struct{}{}
+	}
+
+	return &scopedRepository{
+		delegate: delegate,
+		allowed:  allowed,
+	}
+}
+
+var _ Repository = (*scopedRepository)(nil)
+
+type scopedRepository struct {
+	delegate Repository
+	allowed  map[string]struct{}
+}
+
+func (r *scopedRepository) checkNamespace(namespace string) error {
+	if _, ok := r.allowed[namespace]; !ok {
+		return fmt.Errorf("namespace %q: %w", namespace, ErrForbidden)
+	}
+	return nil
+}
+
+func (r *scopedRepository) AddModule(module *spec.Module) error {
+	if module == nil {
+		return r.delegate.AddModule(module)
+	}
+	if err := r.checkNamespace(module.Namespace); err != nil {
+		return err
+	}
+	return r.delegate.AddModule(module)
+}
+
+func (r *scopedRepository) DeleteNamespace(namespace string, opts ...DeleteOption) error {
+	if err := r.checkNamespace(namespace); err != nil {
+		return err
+	}
+	return r.delegate.DeleteNamespace(namespace, opts...)
+}
+
+func (r *scopedRepository) DeleteModule(namespace string, name string, opts ...DeleteOption) error {
+	if err := r.checkNamespace(namespace); err != nil {
+		return err
+	}
+	return r.delegate.DeleteModule(namespace, name, opts...)
+}
+
+func (r *scopedRepository) DeleteModuleType(namespace string, name string, type_ string, opts ...DeleteOption) error {
+	if err := r.checkNamespace(namespace); err != nil {
+		return err
+	}
+	return r.delegate.DeleteModuleType(namespace, name, type_, opts...)
+}
+
+func (r *scopedRepository) DeleteModuleVersion(namespace string, name string, type_ string, version string, opts ...DeleteOption) error {
+	if err := r.checkNamespace(namespace); err != nil {
+		return err
+	}
+	return r.delegate.DeleteModuleVersion(namespace, name, type_, version, opts...)
+}
+
+func (r *scopedRepository) GetModule(namespace string, name string, type_ string, version string) (*spec.Module, error) {
+	if err := r.checkNamespace(namespace); err != nil {
+		return nil, err
+	}
+	return r.delegate.GetModule(namespace, name, type_, version)
+}
+
+func (r *scopedRepository) HasModule(namespace string, name string, type_ string, version string) (bool, error) {
+	if err := r.checkNamespace(namespace); err != nil {
+		return false, err
+	}
+	return r.delegate.HasModule(namespace, name, type_, version)
+}
+
+func (r *scopedRepository) GetModules(refs []ModuleRef) (map[ModuleRef]*spec.Module, map[ModuleRef]error) {
+	errs := make(map[ModuleRef]error)
+
+	var allowedRefs []ModuleRef
+	for _, ref := range refs {
+		if err := r.checkNamespace(ref.Namespace); err != nil {
+			errs[ref] = err
+			continue
+		}
+		allowedRefs = append(allowedRefs, ref)
+	}
+
+	modules, delegateErrs := r.delegate.GetModules(allowedRefs)
+	for ref, err := range delegateErrs {
+		errs[ref] = err
+	}
+
+	return modules, errs
+}
+
+func (r *scopedRepository) GetModuleHistory(namespace string, name string, type_ string) ([]*spec.Module, error) {
+	if err := r.checkNamespace(namespace); err != nil {
+		return nil, err
+	}
+	return r.delegate.GetModuleHistory(namespace, name, type_)
+}
+
+func (r *scopedRepository) ListModuleNamespaces(opts ...ListOption) ([]string, error) {
+	namespaces, err := r.delegate.ListModuleNamespaces(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	var allowed []string
+	for _, namespace := range namespaces {
+		if _, ok := r.allowed[namespace]; ok {
+			allowed = append(allowed, namespace)
+		}
+	}
+
+	return allowed, nil
+}
+
+func (r *scopedRepository) ListModuleNames(namespace string, opts ...ListOption) ([]string, error) {
+	if err := r.checkNamespace(namespace); err != nil {
+		return nil, err
+	}
+	return r.delegate.ListModuleNames(namespace, opts...)
+}
+
+func (r *scopedRepository) ListModuleTypes(namespace string, name string, opts ...ListOption) ([]string, error) {
+	if err := r.checkNamespace(namespace); err != nil {
+		return nil, err
+	}
+	return r.delegate.ListModuleTypes(namespace, name, opts...)
+}
+
+func (r *scopedRepository) ListModuleVersions(namespace string, name string, type_ string, opts ...ListOption) ([]string, error) {
+	if err := r.checkNamespace(namespace); err != nil {
+		return nil, err
+	}
+	return r.delegate.ListModuleVersions(namespace, name, type_, opts...)
+}
+
+func (r *scopedRepository) Ping(ctx context.Context) error {
+	return r.delegate.Ping(ctx)
+}
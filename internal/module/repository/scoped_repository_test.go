@@ -0,0 +1,132 @@
+/*
+Copyright © 2021 The OpenDependency Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repository
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	spec "github.com/opendependency/go-spec/pkg/spec/v1"
+)
+
+var _ = Describe("scoped repository", func() {
+	var (
+		delegate *inMemoryRepository
+		repo     *scopedRepository
+	)
+
+	newModule := func(namespace string) *spec.Module {
+		return &spec.Module{
+			Namespace: namespace,
+			Name:      "product",
+			Type:      "go",
+			Version: &spec.ModuleVersion{
+				Name: "v1.0.0",
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		delegate = NewInMemoryRepository()
+		Expect(delegate.AddModule(newModule("com.example"))).To(BeNil())
+		Expect(delegate.AddModule(newModule("com.other"))).To(BeNil())
+
+		repo = NewScopedRepository(delegate, []string{"com.example"})
+	})
+
+	Context("in-scope access", func() {
+		It("gets the module", func() {
+			m, err := repo.GetModule("com.example", "product", "go", "v1.0.0")
+			Expect(err).To(BeNil())
+			Expect(m.Namespace).To(Equal("com.example"))
+		})
+
+		It("adds the module", func() {
+			module := newModule("com.example")
+			module.Version.Name = "v1.1.0"
+
+			Expect(repo.AddModule(module)).To(BeNil())
+
+			versions, err := delegate.ListModuleVersions("com.example", "product", "go")
+			Expect(err).To(BeNil())
+			Expect(versions).To(ConsistOf("v1.0.0", "v1.1.0"))
+		})
+	})
+
+	Context("out-of-scope access", func() {
+		It("returns forbidden on get", func() {
+			m, err := repo.GetModule("com.other", "product", "go", "v1.0.0")
+			Expect(m).To(BeNil())
+			Expect(errors.Is(err, ErrForbidden)).To(BeTrue())
+		})
+
+		It("returns forbidden on add", func() {
+			err := repo.AddModule(newModule("com.unknown"))
+			Expect(errors.Is(err, ErrForbidden)).To(BeTrue())
+
+			namespaces, err := delegate.ListModuleNamespaces()
+			Expect(err).To(BeNil())
+			Expect(namespaces).ToNot(ContainElement("com.unknown"))
+		})
+
+		It("returns forbidden on delete", func() {
+			Expect(errors.Is(repo.DeleteNamespace("com.other"), ErrForbidden)).To(BeTrue())
+
+			_, err := delegate.GetModule("com.other", "product", "go", "v1.0.0")
+			Expect(err).To(BeNil())
+		})
+
+		It("returns forbidden on has module", func() {
+			ok, err := repo.HasModule("com.other", "product", "go", "v1.0.0")
+			Expect(ok).To(BeFalse())
+			Expect(errors.Is(err, ErrForbidden)).To(BeTrue())
+		})
+
+		It("returns forbidden on list", func() {
+			names, err := repo.ListModuleNames("com.other")
+			Expect(names).To(BeNil())
+			Expect(errors.Is(err, ErrForbidden)).To(BeTrue())
+		})
+
+		It("returns forbidden per reference on get modules", func() {
+			inScope := ModuleRef{Namespace: "com.example", Name: "product", Type: "go", Version: "v1.0.0"}
+			outOfScope := ModuleRef{Namespace: "com.other", Name: "product", Type: "go", Version: "v1.0.0"}
+
+			modules, errs := repo.GetModules([]ModuleRef{inScope, outOfScope})
+			Expect(modules).To(HaveLen(1))
+			Expect(modules).To(HaveKey(inScope))
+			Expect(errs).To(HaveLen(1))
+			Expect(errors.Is(errs[outOfScope], ErrForbidden)).To(BeTrue())
+		})
+	})
+
+	Context("ping", func() {
+		It("delegates to the underlying repository", func() {
+			Expect(repo.Ping(context.Background())).To(BeNil())
+		})
+	})
+
+	Context("list module namespaces", func() {
+		It("lists only allowed namespaces", func() {
+			namespaces, err := repo.ListModuleNamespaces()
+			Expect(err).To(BeNil())
+			Expect(namespaces).To(Equal([]string{"com.example"}))
+		})
+	})
+})
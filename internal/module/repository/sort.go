@@ -0,0 +1,53 @@
+/*
+Copyright © 2021 The OpenDependency Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repository
+
+import (
+	"sort"
+	"strings"
+
+	spec "github.com/opendependency/go-spec/pkg/spec/v1"
+	"github.com/opendependency/odep/internal/module/semver"
+)
+
+// sortModulesByVersionDesc sorts the given modules by version, newest first.
+func sortModulesByVersionDesc(modules []*spec.Module) {
+	sort.SliceStable(modules, func(i, j int) bool {
+		return compareModuleVersions(modules[i], modules[j]) > 0
+	})
+}
+
+// compareModuleVersions compares the version of module a with the version of module b.
+// Versions are compared by semantic version precedence if both are semantic versions
+// and none declares another version schema, otherwise they are compared lexically.
+func compareModuleVersions(a *spec.Module, b *spec.Module) int {
+	if isSemverSchema(a.Version) && isSemverSchema(b.Version) {
+		av, aErr := semver.Parse(a.Version.Name)
+		bv, bErr := semver.Parse(b.Version.Name)
+		if aErr == nil && bErr == nil {
+			if c := semver.Compare(av, bv); c != 0 {
+				return c
+			}
+		}
+	}
+
+	return strings.Compare(a.Version.GetName(), b.Version.GetName())
+}
+
+func isSemverSchema(version *spec.ModuleVersion) bool {
+	return version != nil && (version.Schema == nil || *version.Schema == semver.Schema)
+}
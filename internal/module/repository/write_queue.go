@@ -0,0 +1,67 @@
+/*
+Copyright © 2021 The OpenDependency Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repository
+
+import (
+	"sync"
+)
+
+// writeQueue serializes in-process writes per directory.
+type writeQueue struct {
+	mux    sync.Mutex
+	queues map[string]*directoryQueue
+}
+
+// directoryQueue serializes the writes to a single directory.
+type directoryQueue struct {
+	// lock holds a token while a writer owns the directory.
+	lock chan struct{}
+	// refs counts the writers owning or waiting for the directory.
+	refs int
+}
+
+func newWriteQueue() *writeQueue {
+	return &writeQueue{
+		queues: map[string]*directoryQueue{},
+	}
+}
+
+// acquire blocks until the caller is the only writer to the given directory within this process.
+// The returned function must be called to let the next writer proceed.
+func (q *writeQueue) acquire(dir string) func() {
+	q.mux.Lock()
+	queue, ok := q.queues[dir]
+	if !ok {
+		queue = &directoryQueue{lock: make(chan struct{}, 1)}
+		q.queues[dir] = queue
+	}
+	queue.refs++
+	q.mux.Unlock()
+
+	queue.lock <- struct{}{}
+	return func() {
+		<-queue.lock
+
+		// forget the directory once no writer owns or waits for it
+		q.mux.Lock()
+		queue.refs--
+		if queue.refs == 0 {
+			delete(q.queues, dir)
+		}
+		q.mux.Unlock()
+	}
+}
@@ -0,0 +1,132 @@
+/*
+Copyright © 2021 The OpenDependency Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package semver
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Schema is the module version schema of semantic versions.
+const Schema = "org.semver.v2"
+
+var semverRegexp = regexp.MustCompile(`^v?(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)` +
+	`(?:-((?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?` +
+	`(?:\+([0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?$`)
+
+// Version represents a semantic version.
+type Version struct {
+	Major      uint64
+	Minor      uint64
+	Patch      uint64
+	Prerelease []string
+	Build      string
+}
+
+// Parse parses the given semantic version with an optional leading "v".
+func Parse(s string) (*Version, error) {
+	match := semverRegexp.FindStringSubmatch(s)
+	if match == nil {
+		return nil, fmt.Errorf("%q is not a valid semantic version", s)
+	}
+
+	v := &Version{
+		Build: match[5],
+	}
+
+	var err error
+	if v.Major, err = strconv.ParseUint(match[1], 10, 64); err != nil {
+		return nil, fmt.Errorf("could not parse major version: %w", err)
+	}
+	if v.Minor, err = strconv.ParseUint(match[2], 10, 64); err != nil {
+		return nil, fmt.Errorf("could not parse minor version: %w", err)
+	}
+	if v.Patch, err = strconv.ParseUint(match[3], 10, 64); err != nil {
+		return nil, fmt.Errorf("could not parse patch version: %w", err)
+	}
+	if match[4] != "" {
+		v.Prerelease = strings.Split(match[4], ".")
+	}
+
+	return v, nil
+}
+
+// Compare compares version a with version b following the semantic version precedence.
+// The result is -1 if a < b, 0 if a == b and +1 if a > b.
+// Build metadata is ignored.
+func Compare(a *Version, b *Version) int {
+	if c := compareUint(a.Major, b.Major); c != 0 {
+		return c
+	}
+	if c := compareUint(a.Minor, b.Minor); c != 0 {
+		return c
+	}
+	if c := compareUint(a.Patch, b.Patch); c != 0 {
+		return c
+	}
+	return comparePrerelease(a.Prerelease, b.Prerelease)
+}
+
+func compareUint(a uint64, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func comparePrerelease(a []string, b []string) int {
+	// a version without pre-release has a higher precedence
+	switch {
+	case len(a) == 0 && len(b) == 0:
+		return 0
+	case len(a) == 0:
+		return 1
+	case len(b) == 0:
+		return -1
+	}
+
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if c := comparePrereleaseIdentifier(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+
+	return compareUint(uint64(len(a)), uint64(len(b)))
+}
+
+func comparePrereleaseIdentifier(a string, b string) int {
+	an, aErr := strconv.ParseUint(a, 10, 64)
+	bn, bErr := strconv.ParseUint(b, 10, 64)
+
+	switch {
+	case aErr == nil && bErr == nil:
+		return compareUint(an, bn)
+	// numeric identifiers have a lower precedence than alphanumeric identifiers
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	default:
+		return strings.Compare(a, b)
+	}
+}
@@ -0,0 +1,90 @@
+/*
+Copyright © 2021 The OpenDependency Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package semver
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("semver", func() {
+
+	Context("parse", func() {
+
+		for _, tt := range []struct {
+			name     string
+			version  string
+			expected Version
+		}{
+			{name: "version has leading v", version: "v1.2.3", expected: Version{Major: 1, Minor: 2, Patch: 3}},
+			{name: "version has no leading v", version: "1.2.3", expected: Version{Major: 1, Minor: 2, Patch: 3}},
+			{name: "version has pre-release", version: "v1.2.3-rc.1", expected: Version{Major: 1, Minor: 2, Patch: 3, Prerelease: []string{"rc", "1"}}},
+			{name: "version has build metadata", version: "v1.2.3+build.5", expected: Version{Major: 1, Minor: 2, Patch: 3, Build: "build.5"}},
+		} {
+			tt := tt
+			When(tt.name, func() {
+				It("returns the parsed version", func() {
+					v, err := Parse(tt.version)
+					Expect(err).To(BeNil())
+					Expect(*v).To(Equal(tt.expected))
+				})
+			})
+		}
+
+		for _, version := range []string{"", "1.2", "v1.2.x", "v01.2.3", "v1.2.3-", "v1.2.3-01"} {
+			version := version
+			When("version is "+version, func() {
+				It("returns an error", func() {
+					v, err := Parse(version)
+					Expect(v).To(BeNil())
+					Expect(err).To(MatchError(ContainSubstring("is not a valid semantic version")))
+				})
+			})
+		}
+	})
+
+	Context("compare", func() {
+
+		for _, tt := range []struct {
+			a        string
+			b        string
+			expected int
+		}{
+			{a: "v1.0.0", b: "v1.0.0", expected: 0},
+			{a: "v1.0.0", b: "v2.0.0", expected: -1},
+			{a: "v1.10.0", b: "v1.9.0", expected: 1},
+			{a: "v1.0.10", b: "v1.0.9", expected: 1},
+			{a: "v1.0.0-rc.1", b: "v1.0.0", expected: -1},
+			{a: "v1.0.0-rc.2", b: "v1.0.0-rc.10", expected: -1},
+			{a: "v1.0.0-alpha", b: "v1.0.0-1", expected: 1},
+			{a: "v1.0.0-alpha", b: "v1.0.0-alpha.1", expected: -1},
+			{a: "v1.0.0+build.1", b: "v1.0.0+build.2", expected: 0},
+		} {
+			tt := tt
+			When(tt.a+" is compared with "+tt.b, func() {
+				It("returns the expected precedence", func() {
+					a, err := Parse(tt.a)
+					Expect(err).To(BeNil())
+					b, err := Parse(tt.b)
+					Expect(err).To(BeNil())
+
+					Expect(Compare(a, b)).To(Equal(tt.expected))
+				})
+			})
+		}
+	})
+})
@@ -0,0 +1,98 @@
+/*
+Copyright © 2021 The OpenDependency Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validation
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	spec "github.com/opendependency/go-spec/pkg/spec/v1"
+	"github.com/opendependency/odep/internal/module/semver"
+)
+
+// ValidateReplacesNoSelfReference validates that the module version does not replace itself.
+func ValidateReplacesNoSelfReference(module *spec.Module) error {
+	if module == nil || module.Version == nil {
+		return nil
+	}
+
+	for _, replaced := range module.Version.Replaces {
+		if replaced == module.Version.Name {
+			return fmt.Errorf("module version %q must not replace itself", module.Version.Name)
+		}
+	}
+
+	return nil
+}
+
+// ValidateVersionSchema validates that the module version name conforms to its version schema.
+// Only the semantic version schema is checked; other schemas are accepted as is.
+func ValidateVersionSchema(module *spec.Module) error {
+	if module == nil || module.Version == nil || module.Version.GetSchema() != semver.Schema {
+		return nil
+	}
+
+	if _, err := semver.Parse(module.Version.Name); err != nil {
+		return fmt.Errorf("module version does not conform to schema %s: %w", semver.Schema, err)
+	}
+
+	return nil
+}
+
+// DefaultUnpinnedVersionPattern matches dependency versions that float instead of
+// referencing an exact version. Its alternatives match, in order:
+//   - a wildcard or comparison operator anywhere, e.g. 1.*, <2.0.0, >=1.0.0, ~1.2.3 and ^1.2.3
+//   - a union of ranges, e.g. 1.0.0 || 2.0.0
+//   - a hyphen range, e.g. 1.0.0 - 2.0.0
+//   - the tag latest in any case
+//   - an x wildcard, e.g. x, 1.x, 1.x.0, v1.x.x and =1.2.X
+//
+// The tag latest and x wildcards only match as whole whitespace separated tokens,
+// so that exact versions merely containing them, e.g. 1.0.0-latest, are not matched.
+var DefaultUnpinnedVersionPattern = regexp.MustCompile(
+	`[*<>~^]|` +
+		`\|\||` +
+		`\s-\s|` +
+		`(?:^|\s)(?i:latest)(?:\s|$)|` +
+		`(?:^|\s)[=v]?(?:\d+\.){0,2}[xX](?:\.[\dxX]+)*(?:\s|$)`,
+)
+
+// ValidateDependenciesPinned validates that no dependency version matches the given unpinned pattern.
+// If pattern is nil, DefaultUnpinnedVersionPattern is used. The error lists every offending dependency.
+func ValidateDependenciesPinned(module *spec.Module, pattern *regexp.Regexp) error {
+	if module == nil {
+		return nil
+	}
+
+	if pattern == nil {
+		pattern = DefaultUnpinnedVersionPattern
+	}
+
+	var unpinned []string
+	for _, dependency := range module.Dependencies {
+		if pattern.MatchString(dependency.Version) {
+			unpinned = append(unpinned, fmt.Sprintf("%s:%s:%s:%s", dependency.Namespace, dependency.Name, dependency.Type, dependency.Version))
+		}
+	}
+
+	if len(unpinned) > 0 {
+		return fmt.Errorf("dependencies are not pinned: %s", strings.Join(unpinned, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,223 @@
+/*
+Copyright © 2021 The OpenDependency Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validation
+
+import (
+	"fmt"
+	"regexp"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	spec "github.com/opendependency/go-spec/pkg/spec/v1"
+	"github.com/opendependency/odep/internal/module/semver"
+)
+
+var _ = Describe("validation", func() {
+
+	Context("validate replaces no self reference", func() {
+
+		var (
+			module *spec.Module
+		)
+
+		BeforeEach(func() {
+			module = &spec.Module{
+				Namespace: "com.example",
+				Name:      "product",
+				Type:      "go",
+				Version: &spec.ModuleVersion{
+					Name: "v1.1.0",
+				},
+			}
+		})
+
+		When("replaces is empty", func() {
+			It("returns nil", func() {
+				Expect(ValidateReplacesNoSelfReference(module)).To(BeNil())
+			})
+		})
+
+		When("replaces contains other versions", func() {
+			It("returns nil", func() {
+				module.Version.Replaces = []string{"v1.0.0", "v1.0.1"}
+
+				Expect(ValidateReplacesNoSelfReference(module)).To(BeNil())
+			})
+		})
+
+		When("replaces contains its own version", func() {
+			It("returns an error", func() {
+				module.Version.Replaces = []string{"v1.0.0", "v1.1.0"}
+
+				err := ValidateReplacesNoSelfReference(module)
+				Expect(err).ToNot(BeNil())
+				Expect(err.Error()).To(Equal(`module version "v1.1.0" must not replace itself`))
+			})
+		})
+	})
+
+	Context("validate dependencies pinned", func() {
+
+		var (
+			module *spec.Module
+		)
+
+		BeforeEach(func() {
+			module = &spec.Module{
+				Namespace: "com.example",
+				Name:      "product",
+				Type:      "go",
+				Version: &spec.ModuleVersion{
+					Name: "v1.0.0",
+				},
+				Dependencies: []*spec.ModuleDependency{
+					{Namespace: "com.example", Name: "lib", Type: "go", Version: "v1.2.3"},
+					{Namespace: "com.example", Name: "chart", Type: "helm", Version: "2.0.0"},
+				},
+			}
+		})
+
+		When("all dependencies are pinned", func() {
+			It("returns nil", func() {
+				Expect(ValidateDependenciesPinned(module, nil)).To(BeNil())
+			})
+		})
+
+		When("dependencies are floating", func() {
+			It("returns an error listing each offending dependency", func() {
+				module.Dependencies = append(module.Dependencies,
+					&spec.ModuleDependency{Namespace: "com.example", Name: "image", Type: "container-image", Version: "latest"},
+					&spec.ModuleDependency{Namespace: "com.example", Name: "util", Type: "go", Version: "^1.0.0"},
+				)
+
+				err := ValidateDependenciesPinned(module, nil)
+				Expect(err).ToNot(BeNil())
+				Expect(err.Error()).To(Equal("dependencies are not pinned: com.example:image:container-image:latest, com.example:util:go:^1.0.0"))
+			})
+		})
+
+		for _, tt := range []struct {
+			version  string
+			unpinned bool
+		}{
+			// wildcards and comparison operators
+			{version: "*", unpinned: true},
+			{version: "1.*", unpinned: true},
+			{version: "<2.0.0", unpinned: true},
+			{version: ">=1.0.0", unpinned: true},
+			{version: "~1.2.3", unpinned: true},
+			{version: "^1.2.3", unpinned: true},
+			// unions of ranges
+			{version: "1.0.0 || 2.0.0", unpinned: true},
+			// hyphen ranges
+			{version: "1.0.0 - 2.0.0", unpinned: true},
+			// latest
+			{version: "latest", unpinned: true},
+			{version: "LATEST", unpinned: true},
+			// x wildcards
+			{version: "x", unpinned: true},
+			{version: "1.x", unpinned: true},
+			{version: "1.x.0", unpinned: true},
+			{version: "v1.x.x", unpinned: true},
+			{version: "=1.2.X", unpinned: true},
+			// exact versions
+			{version: "1.0.0-2.0.0", unpinned: false},
+			{version: "v1.2.3", unpinned: false},
+			{version: "1.0.0-latest", unpinned: false},
+			{version: "latest-2021.10", unpinned: false},
+			{version: "1.0.0-x.1", unpinned: false},
+			{version: "v1.0.0-rc.1", unpinned: false},
+			{version: "sha256-x", unpinned: false},
+		} {
+			tt := tt
+			When(fmt.Sprintf("dependency version is %q", tt.version), func() {
+				It(fmt.Sprintf("reports unpinned as %t", tt.unpinned), func() {
+					module.Dependencies = []*spec.ModuleDependency{
+						{Namespace: "com.example", Name: "lib", Type: "go", Version: tt.version},
+					}
+
+					err := ValidateDependenciesPinned(module, nil)
+					if tt.unpinned {
+						Expect(err).ToNot(BeNil())
+					} else {
+						Expect(err).To(BeNil())
+					}
+				})
+			})
+		}
+
+		When("a custom pattern is given", func() {
+			It("uses the custom pattern", func() {
+				err := ValidateDependenciesPinned(module, regexp.MustCompile(`^\d`))
+				Expect(err).ToNot(BeNil())
+				Expect(err.Error()).To(Equal("dependencies are not pinned: com.example:chart:helm:2.0.0"))
+			})
+		})
+	})
+
+	Context("validate version schema", func() {
+
+		var (
+			module *spec.Module
+		)
+
+		newModule := func(version string, schema *string) *spec.Module {
+			return &spec.Module{
+				Namespace: "com.example",
+				Name:      "product",
+				Type:      "go",
+				Version: &spec.ModuleVersion{
+					Name:   version,
+					Schema: schema,
+				},
+			}
+		}
+
+		When("schema is semantic version", func() {
+			It("accepts a valid semantic version", func() {
+				schema := semver.Schema
+				module = newModule("v1.2.3", &schema)
+
+				Expect(ValidateVersionSchema(module)).To(BeNil())
+			})
+
+			It("rejects an invalid semantic version", func() {
+				schema := semver.Schema
+				module = newModule("1.2", &schema)
+
+				Expect(ValidateVersionSchema(module)).To(MatchError(`module version does not conform to schema org.semver.v2: "1.2" is not a valid semantic version`))
+			})
+		})
+
+		When("schema is not semantic version", func() {
+			It("skips the check", func() {
+				schema := "com.example.calver"
+				module = newModule("1.2", &schema)
+
+				Expect(ValidateVersionSchema(module)).To(BeNil())
+			})
+		})
+
+		When("schema is not set", func() {
+			It("skips the check", func() {
+				module = newModule("1.2", nil)
+
+				Expect(ValidateVersionSchema(module)).To(BeNil())
+			})
+		})
+	})
+})
@@ -0,0 +1,87 @@
+/*
+Copyright © 2021 The OpenDependency Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package multierror
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// MultiError accumulates multiple errors, each with an optional context such as a module coordinate or a file name.
+// The zero value is ready to use.
+type MultiError struct {
+	errs []error
+}
+
+// Append adds the given error with the given context. Nil errors are ignored.
+func (m *MultiError) Append(context string, err error) {
+	if err == nil {
+		return
+	}
+	if context != "" {
+		err = fmt.Errorf("%s: %w", context, err)
+	}
+	m.errs = append(m.errs, err)
+}
+
+// Errors returns all accumulated errors.
+func (m *MultiError) Errors() []error {
+	return m.errs
+}
+
+// ErrorOrNil returns nil if no errors have been accumulated, the multi error itself otherwise.
+func (m *MultiError) ErrorOrNil() error {
+	if m == nil || len(m.errs) == 0 {
+		return nil
+	}
+	return m
+}
+
+func (m *MultiError) Error() string {
+	if len(m.errs) == 1 {
+		return m.errs[0].Error()
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d errors occurred:", len(m.errs))
+	for _, err := range m.errs {
+		b.WriteString("\n  - ")
+		b.WriteString(err.Error())
+	}
+	return b.String()
+}
+
+// Is reports whether any of the accumulated errors matches target.
+func (m *MultiError) Is(target error) bool {
+	for _, err := range m.errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first accumulated error that matches target.
+func (m *MultiError) As(target interface{}) bool {
+	for _, err := range m.errs {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+/*
+Copyright © 2021 The OpenDependency Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package multierror
+
+import (
+	"errors"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("multi error", func() {
+
+	var (
+		errNotFound = errors.New("not found")
+		m           *MultiError
+	)
+
+	BeforeEach(func() {
+		m = &MultiError{}
+	})
+
+	When("no error is appended", func() {
+		It("returns nil", func() {
+			Expect(m.ErrorOrNil()).To(BeNil())
+		})
+
+		It("ignores nil errors", func() {
+			m.Append("com.example:product:go:v1.0.0", nil)
+
+			Expect(m.ErrorOrNil()).To(BeNil())
+			Expect(m.Errors()).To(BeEmpty())
+		})
+	})
+
+	When("a single error is appended", func() {
+		It("formats the error with its context", func() {
+			m.Append("com.example:product:go:v1.0.0", errNotFound)
+
+			Expect(m.ErrorOrNil()).To(MatchError("com.example:product:go:v1.0.0: not found"))
+		})
+
+		It("formats the error without context", func() {
+			m.Append("", errNotFound)
+
+			Expect(m.ErrorOrNil()).To(MatchError("not found"))
+		})
+	})
+
+	When("multiple errors are appended", func() {
+		BeforeEach(func() {
+			m.Append("com.example:product:go:v1.0.0", errNotFound)
+			m.Append("module.json", &os.PathError{Op: "open", Path: "module.json", Err: os.ErrNotExist})
+		})
+
+		It("formats all errors on separate lines", func() {
+			Expect(m.ErrorOrNil()).To(MatchError("2 errors occurred:\n" +
+				"  - com.example:product:go:v1.0.0: not found\n" +
+				"  - module.json: open module.json: file does not exist"))
+		})
+
+		It("unwraps constituent errors with errors.Is", func() {
+			err := m.ErrorOrNil()
+
+			Expect(errors.Is(err, errNotFound)).To(BeTrue())
+			Expect(errors.Is(err, os.ErrNotExist)).To(BeTrue())
+			Expect(errors.Is(err, os.ErrExist)).To(BeFalse())
+		})
+
+		It("unwraps constituent errors with errors.As", func() {
+			var pathErr *os.PathError
+
+			Expect(errors.As(m.ErrorOrNil(), &pathErr)).To(BeTrue())
+			Expect(pathErr.Path).To(Equal("module.json"))
+		})
+	})
+})